@@ -1,7 +1,6 @@
 package main
 
 import (
-	"os"
 	"time"
 
 	"github.com/awesome-gocui/gocui"
@@ -15,13 +14,86 @@ const (
 	MAIN_VIEW    = "main"
 	STATUS_VIEW  = "status"
 	INPUT_VIEW   = "input"
+	FIND_VIEW    = "find"
+	REPLACE_VIEW = "replace"
+	PICKER_VIEW  = "picker"
 	NOTES_DIR    = "notes"
 
+	PICKER_INPUT_VIEW = "picker_input"
+	PICKER_MAX_ROWS   = 12 // Picker list height
+	COMPLETE_VIEW     = "complete"
+	PREVIEW_VIEW      = "preview"
+	GUTTER_VIEW       = "gutter"
+	COMPLETE_MAX_ROWS = 8 // Autocomplete popup height
+	MAX_HEADING_LEVEL = 6 // Deepest heading reached when cycling heading levels
+
+	// Find and selection highlighting (ANSI escapes understood by gocui views)
+	FIND_MATCH_STYLE   = "\x1b[30;43m" // black on yellow
+	FIND_CURRENT_STYLE = "\x1b[30;46m" // black on cyan
+	VIM_VISUAL_STYLE   = "\x1b[7m"     // reverse video
+	ANSI_RESET         = "\x1b[0m"
+
+	// List editing constants
+	LIST_INDENT       = "  " // Indent added by Tab in edit mode
+	LIST_INDENT_WIDTH = 2
+
+	// Crash recovery constants
+	RECOVERY_DIR      = "recovery" // swap file directory in the user cache directory
+	SWAP_EXT          = ".swp"     // swap file extension
+	SWAP_INTERVAL_MS  = 2000       // How often the edit buffer is written to its swap file
+	DIFF_CONTEXT      = 2          // Unchanged lines shown around each change in a diff
+	DIFF_MAX_CELLS    = 4000000    // Largest line-by-line comparison before falling back
+	DIFF_DELETE_STYLE = "\x1b[31m" // red
+	DIFF_INSERT_STYLE = "\x1b[32m" // green
+
+	// Keymap constants
+	GLOBAL_SCOPE = "global"      // keymap scope for bindings active in every view
+	KEYMAP_FILE  = "keymap.json" // keymap file name in the user config directory
+
 	// Large file constants
 	LARGE_FILE_THRESHOLD = 1024 * 1024 // 1MB
 	DEFAULT_CHUNK_SIZE   = 64 * 1024   // 64KB chunks
 	CACHE_LINES          = 1000        // Lines to cache around current position
-	DEFAULT_VIEWPORT     = 30          // Default viewport height
+	LARGE_EDIT_WINDOW    = 1000        // Lines of a large file held in the editor at once
+	LINE_INDEX_INTERVAL  = 1000        // Lines between entries of a large file's offset index
+	LINE_INDEX_DIR       = "index"     // line index directory in the user cache directory
+	INDEX_PROGRESS_MS    = 100         // Milliseconds between indexing progress updates
+	FOLLOW_POLL_MS       = 500         // Milliseconds between checks of a followed file
+
+	// Long line constants (large files)
+	LINE_SEGMENT_BYTES   = 4096      // Bytes of each line cached around the horizontal scroll
+	LONG_LINE_WRAP_BYTES = 64 * 1024 // Most of a line shown when it is wrapped
+	LINE_CUT_MARKER      = "»"       // marks a line going on past the right edge
+	LINE_CUT_LEFT_MARKER = "«"       // marks a line scrolled past the left edge
+
+	// Binary file constants
+	BINARY_SNIFF_BYTES = 8192 // Bytes read to tell a binary file from text
+	HEXDUMP_ROW_BYTES  = 16   // Bytes shown on each hexdump row
+
+	// Spell checking constants
+	DEFAULT_DICT_DIR      = "/usr/share/hunspell" // where Hunspell .dic/.aff files are looked up
+	DEFAULT_DICT_LANG     = "en_US"               // dictionary name without extension
+	PERSONAL_DICT_FILE    = ".dictionary"         // personal word list in the vault
+	SPELL_MAX_SUGGESTIONS = 8                     // Corrections offered for a misspelled word
+	SPELL_DEBOUNCE_MS     = 400                   // Delay after the last keystroke before re-checking
+	SPELL_ERROR_STYLE     = "\x1b[4;31m"          // red underline
+
+	// Snippet constants
+	SNIPPETS_FILE       = "snippets.md" // snippet definitions in the vault
+	SNIPPET_DATE_FORMAT = "2006-01-02"  // {{date}}
+	SNIPPET_TIME_FORMAT = "15:04"       // {{time}}
+
+	// Clipboard constants
+	CLIPBOARD_HISTORY     = 20 // Copies kept for the clipboard history picker
+	CLIPBOARD_PREVIEW_LEN = 50 // Characters of a copy shown in the picker
+
+	// Line number constants
+	GUTTER_MIN_DIGITS = 3  // Narrowest line-number gutter, in digits
+	DEFAULT_VIEWPORT  = 30 // Default viewport height
+
+	// Live preview constants
+	PREVIEW_MIN_WIDTH   = 80  // Main area width needed to show the preview beside the editor
+	PREVIEW_DEBOUNCE_MS = 250 // Delay after the last keystroke before the preview refreshes
 
 	// Responsive design constants
 	SMALL_SCREEN_WIDTH = 80 // Width threshold for small screens
@@ -59,6 +131,9 @@ type App struct {
 	dialogPrompt   string
 	dialogCallback func(string) error
 
+	// Sidebar scroll position and type-ahead
+	sidebar sidebarState
+
 	// Mouse double-click detection
 	lastClickTime time.Time
 	lastClickItem int
@@ -82,20 +157,100 @@ type App struct {
 	currentChunk int
 	totalChunks  int
 	chunkSize    int
-	fileHandle   *os.File
+	fileHandle   largeFile
+	mapFiles     bool // memory-map large files where the platform supports it
 
 	// Smooth scrolling support
-	currentLine    int
-	totalLines     int
-	viewportHeight int
-	lineCache      []string
-	cacheStartLine int
-	cacheEndLine   int
+	currentLine     int
+	totalLines      int
+	viewportHeight  int
+	lineCache       []string
+	lineCut         []bool // cached lines that go on past their segment
+	cacheStartLine  int
+	cacheEndLine    int
+	cacheColumn     int  // byte where cached line segments start (-1: whole lines)
+	hscroll         int  // horizontal scroll of the large-file viewer, in bytes
+	wrapCurrentLine bool // show the top line of the viewer in full
+
+	// Sparse line offset indexes of large files, by absolute path
+	lineIndex    *lineIndex
+	lineIndexes  map[string]*lineIndex
+	lineIndexDir string    // where indexes are kept between runs
+	indexJob     *indexJob // index being built for the open file, if any
+
+	// Binary file shown as a card or hexdump (nil for text files)
+	binary *binaryFile
+
+	// Follow mode (nil unless following the current file)
+	follow *followState
+
+	// Large file editing (nil unless editing a large file)
+	largeEdit *largeEditState
+
+	// External editor
+	externalEditPath string // note handed to $EDITOR while the GUI is closed
+	resumeView       string // view to focus when the GUI restarts
+	statusNote       string // message for the status bar, e.g. a failed editor, until the next selection
+
+	// Crash recovery and autosave
+	recoveryDir      string         // where swap files for edit buffers are kept
+	autosaveIdle     time.Duration  // save after the buffer is idle this long (0 disables)
+	editPath         string         // note being edited, relative to notesDir
+	swapContent      string         // edit buffer as of the last recovery tick
+	lastBufferChange time.Time      // when the edit buffer last changed
+	pendingRecovery  []recoveryFile // leftover swap files offered at startup
+
+	// Find and replace
+	find findState
+
+	// Picker overlay (insert link, ...)
+	picker pickerState
+
+	// Autocomplete popup for [[links]] and #tags
+	complete completeState
+	vault    vaultLists // note titles and tags offered while typing
+
+	// Rendered markdown of the last note shown
+	render renderCache
+
+	// Live preview beside the editor
+	previewEnabled bool
+	previewDirty   bool        // preview needs a re-render on the next layout
+	previewTimer   *time.Timer // debounces re-rendering while typing
+	previewRows    []int       // wrapped row where each rendered line starts
+	previewSync    [3]int      // editor cursor and origin the preview was last synced to
+
+	// Snippets from the vault and the tab stops of the last expansion
+	snippets []snippet
+	snippet  snippetSession
+
+	// Clipboard backend, built-in register and history
+	clipboard clipboardState
+
+	// Spell checking (nil when disabled)
+	spell      *hunspellDict
+	spellTimer *time.Timer // debounces re-checking while typing
+
+	// Column kept by up/down movement across wrapped rows
+	goal cursorGoal
+
+	// Line-number gutter beside the main view
+	lineNumbers bool
+
+	// Key bindings (scope -> action -> keys)
+	keymap Keymap
+
+	// Vim-style modal editing (opt-in)
+	vimEnabled bool
+	vim        vimState
+
+	// Messages from timers and background jobs to the main loop
+	events *eventQueue
 }
 
 // NewApp creates a new application instance
 func NewApp() *App {
-	return &App{
+	app := &App{
 		currentItem:   0,
 		isEditMode:    false,
 		notesDir:      NOTES_DIR,
@@ -103,6 +258,10 @@ func NewApp() *App {
 		noteTitles:    make(map[string]string),
 		lastClickItem: -1, // Initialize to invalid index
 		chunkSize:     DEFAULT_CHUNK_SIZE,
+		keymap:        defaultKeymap(),
+		clipboard:     clipboardState{mode: clipboardAuto},
+		lineIndexes:   make(map[string]*lineIndex),
+		lineIndexDir:  defaultLineIndexDir(),
 
 		// Initialize responsive design
 		sidebarVisible: true,
@@ -117,4 +276,6 @@ func NewApp() *App {
 		forceLayout:      false,
 		viewsInitialized: false,
 	}
+	app.events = &eventQueue{handle: app.dispatch}
+	return app
 }
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"fmt"
+	"image"
+	_ "image/gif" // Register decoders for image dimensions
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// =============================================================================
+// BINARY FILES
+// =============================================================================
+
+// binaryFile describes a non-text file shown in the main view
+type binaryFile struct {
+	path     string
+	size     int64
+	modTime  time.Time
+	mimeType string
+	image    string // dimensions and format of an image, "" when not decodable
+	hexdump  bool   // page through the bytes instead of showing the card
+	offset   int64  // first byte of the hexdump shown
+}
+
+// sniffBinary reads the start of a file and reports whether it is binary,
+// along with the MIME type detected from its content
+func sniffBinary(path string) (bool, string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return false, "", err
+	}
+	defer file.Close()
+
+	head := make([]byte, BINARY_SNIFF_BYTES)
+	n, err := io.ReadFull(file, head)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return false, "", err
+	}
+
+	// The MIME type is only a label: DetectContentType looks at 512 bytes
+	return hasControlBytes(head[:n]), http.DetectContentType(head[:n]), nil
+}
+
+// hasControlBytes reports whether data holds a NUL or a control byte text
+// doesn't use. Bytes above 0x7f are allowed, so text in legacy encodings
+// such as Latin-1 still counts as text.
+func hasControlBytes(data []byte) bool {
+	for _, c := range data {
+		switch {
+		case c == '\t', c == '\n', c == '\r', c == '\f', c == '\v', c == 0x1b: // 0x1b starts ANSI colors in logs
+		case c < 0x20, c == 0x7f:
+			return true
+		}
+	}
+	return false
+}
+
+// loadBinaryFile shows a binary file's card (or hexdump) and returns true, or
+// returns false for text files
+func (app *App) loadBinaryFile(path string) bool {
+	binary, sniffed, err := sniffBinary(path)
+	if err != nil || !binary {
+		return false
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return false
+	}
+
+	app.cancelLineIndexing()
+	app.cancelLargeSearch()
+	app.stopFollow()
+	app.closeFile()
+	app.isLargeFile = false
+	app.fileSize = info.Size()
+
+	b := &binaryFile{path: path, size: info.Size(), modTime: info.ModTime(), mimeType: sniffed}
+	if byExt := mime.TypeByExtension(filepath.Ext(path)); byExt != "" {
+		b.mimeType = byExt // More specific than sniffing for zip-based formats
+	}
+	if file, err := os.Open(path); err == nil {
+		if config, format, err := image.DecodeConfig(file); err == nil {
+			b.image = fmt.Sprintf("%d × %d (%s)", config.Width, config.Height, format)
+		}
+		file.Close()
+	}
+
+	// Reselecting the same file keeps its hexdump position
+	if prev := app.binary; prev != nil && prev.path == path && prev.offset < b.size {
+		b.hexdump, b.offset = prev.hexdump, prev.offset
+	}
+	app.binary = b
+	app.currentContent = app.binaryContent()
+	return true
+}
+
+// binaryContent returns the text shown for the binary file: its card or a
+// page of its hexdump
+func (app *App) binaryContent() string {
+	b := app.binary
+	if b.hexdump {
+		return app.hexdumpPage()
+	}
+
+	lines := []string{
+		"📦 " + filepath.Base(b.path),
+		"",
+		"Size:      " + formatSize(b.size),
+		"Type:      " + b.mimeType,
+		"Modified:  " + b.modTime.Format("2006-01-02 15:04:05"),
+	}
+	if b.image != "" {
+		lines = append(lines, "Image:     "+b.image)
+	}
+	lines = append(lines, "",
+		"Binary file, not shown as text. "+app.formatKeyHints(", ",
+			keyHintSpec{MAIN_VIEW, []string{"hexdump"}, "Hexdump"},
+			keyHintSpec{MAIN_VIEW, []string{"external_edit"}, "$EDITOR"}))
+	return strings.Join(lines, "\n")
+}
+
+// hexdumpPage formats the rows of the hexdump that fill the main view, like
+// `hexdump -C`
+func (app *App) hexdumpPage() string {
+	b := app.binary
+	file, err := os.Open(b.path)
+	if err != nil {
+		return "Cannot read " + filepath.Base(b.path) + ": " + err.Error()
+	}
+	defer file.Close()
+
+	buf := make([]byte, app.hexdumpRows()*HEXDUMP_ROW_BYTES)
+	n, err := file.ReadAt(buf, b.offset)
+	if err != nil && err != io.EOF {
+		return "Cannot read " + filepath.Base(b.path) + ": " + err.Error()
+	}
+
+	var out strings.Builder
+	for row := 0; row < n; row += HEXDUMP_ROW_BYTES {
+		end := row + HEXDUMP_ROW_BYTES
+		if end > n {
+			end = n
+		}
+		fmt.Fprintf(&out, "%08x  ", b.offset+int64(row))
+		for i := row; i < row+HEXDUMP_ROW_BYTES; i++ {
+			if i < end {
+				fmt.Fprintf(&out, "%02x ", buf[i])
+			} else {
+				out.WriteString("   ")
+			}
+			if i == row+HEXDUMP_ROW_BYTES/2-1 {
+				out.WriteByte(' ')
+			}
+		}
+		out.WriteString(" |")
+		for _, c := range buf[row:end] {
+			if c < 0x20 || c > 0x7e {
+				c = '.'
+			}
+			out.WriteByte(c)
+		}
+		out.WriteString("|\n")
+	}
+	return out.String()
+}
+
+// hexdumpRows returns how many hexdump rows fit in the main view
+func (app *App) hexdumpRows() int {
+	rows := DEFAULT_VIEWPORT
+	if v, err := app.gui.View(MAIN_VIEW); err == nil {
+		if _, h := v.Size(); h > 0 {
+			rows = h
+		}
+	}
+	return rows
+}
+
+// toggleHexdump switches a binary file between its card and its hexdump
+func (app *App) toggleHexdump(g *gocui.Gui, v *gocui.View) error {
+	if app.binary == nil || app.isEditMode {
+		return nil
+	}
+	app.binary.hexdump = !app.binary.hexdump
+	app.currentContent = app.binaryContent()
+	app.updateMainView()
+	app.updateStatusBar()
+	return nil
+}
+
+// scrollHexdump moves the hexdump by rows, clamped to the file
+func (app *App) scrollHexdump(rows int) error {
+	b := app.binary
+	last := (b.size - 1) / HEXDUMP_ROW_BYTES * HEXDUMP_ROW_BYTES
+	bottom := last - int64(app.hexdumpRows()-1)*HEXDUMP_ROW_BYTES
+	offset := b.offset + int64(rows)*HEXDUMP_ROW_BYTES
+	if offset > bottom {
+		offset = bottom
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	if offset == b.offset {
+		return nil
+	}
+
+	b.offset = offset
+	app.currentContent = app.hexdumpPage()
+	app.updateMainView()
+	app.updateStatusBar()
+	return nil
+}
+
+// formatSize returns a byte count in human units, e.g. "1.5 MB (1572864 bytes)"
+func formatSize(size int64) string {
+	if size < 1024 {
+		return fmt.Sprintf("%d bytes", size)
+	}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < 4 {
+		value /= 1024
+		unit++
+	}
+	return fmt.Sprintf("%.1f %s (%d bytes)", value, []string{"B", "KB", "MB", "GB", "TB"}[unit], size)
+}
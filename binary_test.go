@@ -0,0 +1,38 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSniffBinary(t *testing.T) {
+	tests := []struct {
+		name, content string
+		binary        bool
+	}{
+		{"empty", "", false},
+		{"markdown", "# Title\n\ttabbed — ünïcode\r\n", false},
+		{"latin-1", "caf\xe9 cr\xe8me\n", false},
+		{"ansi log", "\x1b[31merror\x1b[0m done\n", false},
+		{"nul past 512 bytes", strings.Repeat("text ", 200) + "\x00\x01\x02", true},
+		{"control byte", strings.Repeat("a", 4000) + "\x07", true},
+		{"png", "\x89PNG\r\n\x1a\n\x00\x00\x00\rIHDR", true},
+		{"beyond the sniffed bytes", strings.Repeat("a", BINARY_SNIFF_BYTES) + "\x00", false},
+	}
+	dir := t.TempDir()
+	for _, tt := range tests {
+		path := filepath.Join(dir, "file")
+		if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		binary, mimeType, err := sniffBinary(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if binary != tt.binary {
+			t.Errorf("%s: binary = %v (%s), want %v", tt.name, binary, mimeType, tt.binary)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// =============================================================================
+// CLIPBOARD BACKENDS
+// =============================================================================
+
+// Clipboard modes selected with -clipboard
+const (
+	clipboardAuto     = "auto"     // system clipboard, then OSC 52 when it is unavailable
+	clipboardSystem   = "system"   // system clipboard only
+	clipboardOSC52    = "osc52"    // terminal clipboard escape sequence (works over SSH)
+	clipboardInternal = "internal" // built-in register only
+)
+
+// clipboardState is the built-in register and the history of copies
+type clipboardState struct {
+	mode     string
+	register string   // last copied text, used when no system clipboard works
+	history  []string // recent copies, newest first
+}
+
+// remember stores a copy in the register and at the top of the history
+func (c *clipboardState) remember(text string) {
+	if text == "" {
+		return
+	}
+	c.register = text
+
+	history := []string{text}
+	for _, old := range c.history {
+		if old != text && len(history) < CLIPBOARD_HISTORY {
+			history = append(history, old)
+		}
+	}
+	c.history = history
+}
+
+// writeOSC52 asks the terminal to put text on the clipboard. Terminals that
+// support it do so even when the app runs on a remote machine over SSH.
+func writeOSC52(text string) error {
+	seq := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte(text)) + "\a"
+	if os.Getenv("TMUX") != "" {
+		// tmux passes the sequence on to the outer terminal when wrapped
+		seq = "\x1bPtmux;" + strings.Replace(seq, "\x1b", "\x1b\x1b", -1) + "\x1b\\"
+	} else if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		seq = "\x1bP" + seq + "\x1b\\"
+	}
+
+	tty, err := os.OpenFile("/dev/tty", os.O_WRONLY, 0)
+	if err != nil {
+		_, err = fmt.Fprint(os.Stdout, seq)
+		return err
+	}
+	defer tty.Close()
+	_, err = fmt.Fprint(tty, seq)
+	return err
+}
+
+// clipboardPreview returns a one-line label for a history entry
+func clipboardPreview(text string) string {
+	label := strings.Replace(strings.TrimSpace(text), "\n", " ⏎ ", -1)
+	if runes := []rune(label); len(runes) > CLIPBOARD_PREVIEW_LEN {
+		label = string(runes[:CLIPBOARD_PREVIEW_LEN]) + "…"
+	}
+	return label
+}
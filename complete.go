@@ -0,0 +1,349 @@
+package main
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// =============================================================================
+// AUTOCOMPLETE
+// =============================================================================
+
+// completeKind is what the autocomplete popup is completing
+type completeKind int
+
+const (
+	completeLink completeKind = iota // [[note title]]
+	completeTag                      // #tag
+)
+
+// completeState holds the autocomplete popup shown while typing [[ or #
+type completeState struct {
+	active   bool
+	kind     completeKind
+	line     int // buffer line of the trigger
+	start    int // rune index of "[[" or "#" in that line
+	items    []pickerItem
+	filtered []pickerItem
+	selected int
+}
+
+// tagRegex matches #tags that start a line or follow whitespace
+var tagRegex = regexp.MustCompile(`(?:^|\s)#([\p{L}\p{N}_][\p{L}\p{N}_/-]*)`)
+
+// isTagRune reports whether r can be part of a tag name
+func isTagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '/' || r == '-'
+}
+
+// completionContext finds a [[ or # being typed before the cursor
+func completionContext(before []rune) (kind completeKind, start int, query string, ok bool) {
+	// [[link: the last "[[" not yet closed
+	for i := len(before) - 2; i >= 0; i-- {
+		if before[i] == ']' {
+			break
+		}
+		if before[i] == '[' && before[i+1] == '[' {
+			return completeLink, i, string(before[i+2:]), true
+		}
+	}
+
+	// #tag: tag characters back to a '#' at line start or after whitespace
+	j := len(before)
+	for j > 0 && isTagRune(before[j-1]) {
+		j--
+	}
+	if j > 0 && before[j-1] == '#' && (j == 1 || unicode.IsSpace(before[j-2])) {
+		return completeTag, j - 1, string(before[j:]), true
+	}
+
+	return 0, 0, "", false
+}
+
+// refreshCompletion opens, filters or closes the popup after an edit
+func (app *App) refreshCompletion(v *gocui.View) {
+	if !app.isEditMode || !v.Editable || (app.vimEnabled && app.vim.mode != vimInsert) {
+		app.closeCompletion()
+		return
+	}
+
+	x, y := v.Cursor()
+	text, _ := v.Line(y)
+	line := []rune(text)
+	if x > len(line) {
+		x = len(line)
+	}
+
+	kind, start, query, ok := completionContext(line[:x])
+	if !ok {
+		app.closeCompletion()
+		return
+	}
+
+	c := &app.complete
+	if !c.active || c.kind != kind || c.line != y || c.start != start {
+		*c = completeState{active: true, kind: kind, line: y, start: start}
+		c.items = app.completionItems(kind)
+	}
+
+	c.filtered = filterPickerItems(c.items, query)
+	c.selected = 0
+	if len(c.filtered) == 0 {
+		app.gui.DeleteView(COMPLETE_VIEW)
+		return
+	}
+	app.layoutCompletion(app.gui)
+}
+
+// vaultLists caches what autocomplete and the link picker offer, so typing
+// never walks the vault. The lists are rebuilt in the background whenever notes
+// are saved, created, renamed or deleted.
+type vaultLists struct {
+	notes []vaultNote
+	links []pickerItem // titles for [[links]]
+	tags  []pickerItem // #tags, most used first
+	build int          // the rebuild whose lists are wanted
+}
+
+// refreshVaultLists rebuilds the cached lists in the background
+func (app *App) refreshVaultLists() {
+	app.vault.build++
+	build := app.vault.build
+	go func() {
+		notes := app.vaultNotes()
+		app.events.post(vaultListsMsg{build, notes, linkCompletions(notes), tagCompletions(app.vaultTags())})
+	}()
+}
+
+// vaultListsBuilt stores rebuilt lists, refreshing an open popup
+func (app *App) vaultListsBuilt(m vaultListsMsg) {
+	if m.build != app.vault.build {
+		return // A newer rebuild is running
+	}
+	app.vault.notes, app.vault.links, app.vault.tags = m.notes, m.links, m.tags
+
+	if c := &app.complete; c.active {
+		c.items = app.completionItems(c.kind)
+		if v, err := app.gui.View(MAIN_VIEW); err == nil {
+			app.refreshCompletion(v)
+		}
+	}
+}
+
+// completionItems returns the cached list for a kind of completion
+func (app *App) completionItems(kind completeKind) []pickerItem {
+	if kind == completeLink {
+		return app.vault.links
+	}
+	return app.vault.tags
+}
+
+// linkCompletions lists the titles of notes
+func linkCompletions(notes []vaultNote) []pickerItem {
+	var items []pickerItem
+	seen := make(map[string]bool)
+	for _, note := range notes {
+		if seen[note.Title] {
+			continue
+		}
+		seen[note.Title] = true
+		items = append(items, pickerItem{label: note.Title, value: note.Title})
+	}
+	return items
+}
+
+// tagCompletions lists tags by their counts, most used first
+func tagCompletions(counts map[string]int) []pickerItem {
+	tags := make([]string, 0, len(counts))
+	for tag := range counts {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		if counts[tags[i]] != counts[tags[j]] {
+			return counts[tags[i]] > counts[tags[j]]
+		}
+		return tags[i] < tags[j]
+	})
+
+	items := make([]pickerItem, len(tags))
+	for i, tag := range tags {
+		items[i] = pickerItem{label: "#" + tag, value: tag}
+	}
+	return items
+}
+
+// vaultTags counts the #tags in every note of the vault, outside fenced code
+func (app *App) vaultTags() map[string]int {
+	counts := make(map[string]int)
+	filepath.Walk(app.notesDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != app.notesDir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if info.Size() > LARGE_FILE_THRESHOLD ||
+			(!strings.HasSuffix(info.Name(), ".md") && !strings.HasSuffix(info.Name(), ".txt")) {
+			return nil
+		}
+
+		content, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil
+		}
+		inFence := false
+		for _, line := range strings.Split(string(content), "\n") {
+			if strings.HasPrefix(strings.TrimSpace(line), "```") {
+				inFence = !inFence
+				continue
+			}
+			if inFence {
+				continue
+			}
+			for _, m := range tagRegex.FindAllStringSubmatch(line, -1) {
+				counts[m[1]]++
+			}
+		}
+		return nil
+	})
+	return counts
+}
+
+// layoutCompletion places the popup under the cursor (or above it near the bottom)
+func (app *App) layoutCompletion(g *gocui.Gui) error {
+	mainView, err := g.View(MAIN_VIEW)
+	if err != nil {
+		app.closeCompletion()
+		return nil
+	}
+	x0, y0, _, _, err := g.ViewPosition(MAIN_VIEW)
+	if err != nil {
+		return nil
+	}
+
+	c := &app.complete
+	width := 20
+	for _, item := range c.filtered {
+		if w := runesWidth([]rune(item.label)) + 4; w > width {
+			width = w
+		}
+	}
+	maxX, maxY := g.Size()
+	if width > maxX-2 {
+		width = maxX - 2
+	}
+	rows := len(c.filtered)
+	if rows > COMPLETE_MAX_ROWS {
+		rows = COMPLETE_MAX_ROWS
+	}
+
+	col, row := cursorScreenPos(mainView)
+	px := x0 + 1 + col
+	py := y0 + 2 + row // first row below the cursor
+	if px+width >= maxX {
+		px = maxX - width - 1
+	}
+	if py+rows+1 >= maxY {
+		py = y0 + row - rows - 1 // no room below: open above the cursor
+	}
+	if px < 0 {
+		px = 0
+	}
+	if py < 0 {
+		py = 0
+	}
+
+	v, err := g.SetView(COMPLETE_VIEW, px, py, px+width, py+rows+1, 0)
+	if err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Highlight = true
+		v.SelBgColor = gocui.ColorCyan
+		v.SelFgColor = gocui.ColorBlack
+	}
+	g.SetViewOnTop(COMPLETE_VIEW)
+
+	v.Clear()
+	for _, item := range c.filtered {
+		fmt.Fprintf(v, " %s\n", item.label)
+	}
+	_, oy := v.Origin()
+	if c.selected < oy {
+		oy = c.selected
+	} else if c.selected >= oy+rows {
+		oy = c.selected - rows + 1
+	}
+	v.SetOrigin(0, oy)
+	v.SetCursor(0, c.selected-oy)
+	return nil
+}
+
+// closeCompletion hides the popup
+func (app *App) closeCompletion() {
+	if !app.complete.active {
+		return
+	}
+	app.complete = completeState{}
+	app.gui.DeleteView(COMPLETE_VIEW)
+}
+
+// completionShown reports whether the popup is open with entries to choose from
+func (app *App) completionShown() bool {
+	return app.complete.active && len(app.complete.filtered) > 0
+}
+
+// moveCompletion moves the popup selection by delta
+func (app *App) moveCompletion(delta int) error {
+	c := &app.complete
+	c.selected += delta
+	if c.selected < 0 {
+		c.selected = len(c.filtered) - 1
+	}
+	if c.selected >= len(c.filtered) {
+		c.selected = 0
+	}
+	return app.layoutCompletion(app.gui)
+}
+
+// acceptCompletion replaces the typed [[ or # text with the selected entry
+func (app *App) acceptCompletion(v *gocui.View) error {
+	c := app.complete
+	app.closeCompletion()
+
+	lines, x, y := editBufferLines(v)
+	if y != c.line {
+		return nil
+	}
+	line := []rune(lines[y])
+	if x > len(line) {
+		x = len(line)
+	}
+
+	item := c.filtered[c.selected]
+	var text string
+	rest := line[x:]
+	if c.kind == completeLink {
+		text = "[[" + item.value + "]]"
+		if strings.HasPrefix(string(rest), "]]") {
+			rest = rest[2:] // Replace an existing closing bracket pair
+		}
+	} else {
+		text = "#" + item.value
+	}
+
+	lines[y] = string(line[:c.start]) + text + string(rest)
+	app.setEditLinesAt(v, lines, c.start+len([]rune(text)), y)
+	return nil
+}
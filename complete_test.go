@@ -0,0 +1,61 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCompletionUsesCachedVaultLists(t *testing.T) {
+	app := newTestApp(t, map[string]string{
+		"alpha.md": "# Alpha\n#travel #travel #todo\n",
+		"beta.md":  "# Beta\n#todo\n```\n#code\n```\n",
+	})
+	selectFile(t, app, "alpha.md")
+	build := app.vault.build
+	app.refreshItems(app.gui, nil)
+	drainUntil(t, app, func() bool { return app.vault.build > build && app.vault.links != nil })
+
+	labels := func(items []pickerItem) []string {
+		var out []string
+		for _, item := range items {
+			out = append(out, item.label)
+		}
+		return out
+	}
+	if got := labels(app.vault.links); len(got) != 2 || got[0] != "Alpha" || got[1] != "Beta" {
+		t.Fatalf("links = %q", got)
+	}
+	if got := labels(app.vault.tags); len(got) != 2 || got[0] != "#todo" || got[1] != "#travel" {
+		t.Fatalf("tags = %q", got)
+	}
+
+	// Typing filters the cached lists without reading the vault
+	v, err := app.gui.View(MAIN_VIEW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.enterEditMode(app.gui, v); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.RemoveAll(filepath.Join(app.notesDir, "beta.md")); err != nil {
+		t.Fatal(err)
+	}
+	v.SetCursor(0, 2)
+	for _, ch := range "#tr" {
+		v.EditWrite(ch)
+		app.refreshCompletion(v)
+	}
+	if got := labels(app.complete.filtered); len(got) != 1 || got[0] != "#travel" {
+		t.Fatalf("completions for #tr = %q", got)
+	}
+
+	// Saving rebuilds the lists in the background
+	if err := app.saveNote(app.gui, v); err != nil {
+		t.Fatal(err)
+	}
+	drainUntil(t, app, func() bool { return len(app.vault.links) == 1 })
+	if got := labels(app.vault.links); got[0] != "Alpha" {
+		t.Errorf("links after saving = %q", got)
+	}
+}
@@ -30,6 +30,13 @@ func (app *App) layoutInputDialog(g *gocui.Gui) error {
 
 	startX := (maxX - dialogWidth) / 2
 	startY := (maxY - dialogHeight) / 2
+	if app.dialogType == "recover" {
+		// Keep the recovery diff in the main view visible above the dialog
+		startY = maxY - dialogHeight - 4
+		if startY < 0 {
+			startY = 0
+		}
+	}
 
 	// Background dialog
 	if v, err := g.SetView("dialog", startX, startY, startX+dialogWidth, startY+dialogHeight, 0); err != nil {
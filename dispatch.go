@@ -0,0 +1,129 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// =============================================================================
+// EVENT DISPATCH
+// =============================================================================
+
+// App state belongs to the GUI's main loop. Timers and background jobs never
+// touch it; they post messages, which dispatch applies on the main loop in the
+// order they were posted.
+
+// Messages posted by background work
+type (
+	startupMsg       struct{ focus string } // the GUI is up
+	recoveryTickMsg  struct{}               // time to refresh the swap file
+	previewMsg       struct{}               // typing paused, refresh the preview
+	spellMsg         struct{}               // typing paused, check spelling
+	followTickMsg    struct{ state *followState }
+	indexProgressMsg struct {
+		job   *indexJob
+		read  int64
+		lines int
+	}
+	indexDoneMsg struct {
+		job *indexJob
+		idx *lineIndex
+		err error
+	}
+	searchBatchMsg struct {
+		search        *largeSearch
+		lines, counts []int
+		done          bool
+	}
+	vaultListsMsg struct {
+		build       int
+		notes       []vaultNote
+		links, tags []pickerItem
+	}
+)
+
+// eventQueue carries messages from any goroutine to the main loop. Messages
+// posted while no GUI is running wait for the next one.
+type eventQueue struct {
+	mu      sync.Mutex
+	gui     *gocui.Gui
+	pending []interface{}
+	handle  func(msg interface{}) error
+}
+
+// post queues a message for the main loop; it is safe from any goroutine
+func (q *eventQueue) post(msg interface{}) {
+	q.mu.Lock()
+	q.pending = append(q.pending, msg)
+	g := q.gui
+	kick := len(q.pending) == 1 && g != nil
+	q.mu.Unlock()
+
+	if kick {
+		g.Update(q.drain)
+	}
+}
+
+// attach delivers messages to a newly started GUI
+func (q *eventQueue) attach(g *gocui.Gui) {
+	q.mu.Lock()
+	q.gui = g
+	kick := len(q.pending) > 0
+	q.mu.Unlock()
+
+	if kick {
+		g.Update(q.drain)
+	}
+}
+
+// detach holds messages back while the GUI is closed
+func (q *eventQueue) detach() {
+	q.mu.Lock()
+	q.gui = nil
+	q.mu.Unlock()
+}
+
+// drain runs on the main loop and handles every queued message in order. A
+// failing message doesn't stop the rest; the first error is returned.
+func (q *eventQueue) drain(g *gocui.Gui) error {
+	q.mu.Lock()
+	msgs := q.pending
+	q.pending = nil
+	q.mu.Unlock()
+
+	var first error
+	for _, msg := range msgs {
+		if err := q.handle(msg); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// dispatch applies a message from background work to the app
+func (app *App) dispatch(msg interface{}) error {
+	switch m := msg.(type) {
+	case startupMsg:
+		return app.startup(m.focus)
+	case recoveryTickMsg:
+		return app.recoveryTick(app.gui)
+	case previewMsg:
+		app.updatePreview()
+	case spellMsg:
+		app.underlineMisspellings()
+	case followTickMsg:
+		if app.follow == m.state {
+			return app.followCheck()
+		}
+	case indexProgressMsg:
+		app.indexProgressed(m)
+	case indexDoneMsg:
+		app.indexFinished(m)
+	case searchBatchMsg:
+		return app.addSearchResults(m.search, m.lines, m.counts, m.done)
+	case vaultListsMsg:
+		app.vaultListsBuilt(m)
+	}
+	return nil
+}
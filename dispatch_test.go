@@ -0,0 +1,293 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// newTestApp returns an app over a vault holding files, laid out on a
+// simulated screen. Its event queue has no GUI attached; tests drain it.
+func newTestApp(t *testing.T, files map[string]string) *App {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	app := NewApp()
+	app.notesDir = dir
+	app.lineIndexDir = t.TempDir()
+	app.recoveryDir = t.TempDir()
+	app.loadItems()
+
+	g, err := gocui.NewGui(gocui.OutputSimulator, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		app.stopFollow()
+		app.cancelLineIndexing()
+		app.cancelLargeSearch()
+		app.closeFile()
+		g.Close()
+	})
+	app.gui = g
+	app.forceLayout = true // The first layout would be skipped as a repeat
+	err = app.layout(g)
+	app.forceLayout = false
+	if err != nil {
+		t.Fatal(err)
+	}
+	return app
+}
+
+// selectFile makes the named vault file the current item
+func selectFile(t *testing.T, app *App, name string) {
+	t.Helper()
+	for i, item := range app.items {
+		if item.Name == name {
+			app.currentItem = i
+			app.loadCurrentItem()
+			return
+		}
+	}
+	t.Fatalf("no item %q", name)
+}
+
+// drainUntil dispatches queued messages on the test goroutine until done
+// reports true
+func drainUntil(t *testing.T, app *App, done func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		if err := app.events.drain(app.gui); err != nil {
+			t.Fatal(err)
+		}
+		if done() {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for background events")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// numberedLines returns n lines of text, each ending in a newline
+func numberedLines(from, n int) string {
+	var b strings.Builder
+	for i := from; i < from+n; i++ {
+		fmt.Fprintf(&b, "line %d of a file that keeps going for a while\n", i)
+	}
+	return b.String()
+}
+
+func TestEventQueueKeepsOrderPerSender(t *testing.T) {
+	type seq struct{ sender, n int }
+	var got []seq
+	q := &eventQueue{handle: func(msg interface{}) error {
+		got = append(got, msg.(seq))
+		return nil
+	}}
+
+	const senders, each = 8, 500
+	var wg sync.WaitGroup
+	for s := 0; s < senders; s++ {
+		wg.Add(1)
+		go func(s int) {
+			defer wg.Done()
+			for n := 0; n < each; n++ {
+				q.post(seq{s, n})
+			}
+		}(s)
+	}
+
+	// Drain while the senders are still posting
+	stop := make(chan struct{})
+	go func() { wg.Wait(); close(stop) }()
+	for running := true; running; {
+		select {
+		case <-stop:
+			running = false
+		default:
+		}
+		q.drain(nil)
+	}
+	q.drain(nil)
+
+	if len(got) != senders*each {
+		t.Fatalf("got %d messages, want %d", len(got), senders*each)
+	}
+	next := make([]int, senders)
+	for _, m := range got {
+		if m.n != next[m.sender] {
+			t.Fatalf("sender %d: got message %d, want %d", m.sender, m.n, next[m.sender])
+		}
+		next[m.sender]++
+	}
+}
+
+func TestEventQueueDrainAfterError(t *testing.T) {
+	failed := errors.New("failed")
+	var handled []int
+	q := &eventQueue{handle: func(msg interface{}) error {
+		handled = append(handled, msg.(int))
+		if msg.(int) == 1 {
+			return failed
+		}
+		return nil
+	}}
+	for i := 0; i < 4; i++ {
+		q.post(i)
+	}
+
+	if err := q.drain(nil); err != failed {
+		t.Fatalf("drain returned %v, want %v", err, failed)
+	}
+	if fmt.Sprint(handled) != "[0 1 2 3]" {
+		t.Fatalf("handled %v, want every message", handled)
+	}
+}
+
+func TestDispatchStartup(t *testing.T) {
+	app := newTestApp(t, map[string]string{"note.md": "# Note\nhello\n"})
+	app.viewsInitialized = false
+	app.currentContent = ""
+
+	go app.events.post(startupMsg{focus: MAIN_VIEW})
+	drainUntil(t, app, func() bool { return app.viewsInitialized })
+
+	if v := app.gui.CurrentView(); v == nil || v.Name() != MAIN_VIEW {
+		t.Fatalf("focus is on %v, want %s", v, MAIN_VIEW)
+	}
+	if app.forceLayout {
+		t.Error("forceLayout left set after startup")
+	}
+	if app.currentContent != "# Note\nhello\n" {
+		t.Errorf("content = %q", app.currentContent)
+	}
+}
+
+func TestDispatchIndexingAndSearch(t *testing.T) {
+	const lines = 30000
+	app := newTestApp(t, map[string]string{"big.log": numberedLines(0, lines)})
+	// The first layout opened the only file. Opening it again could find the
+	// index its cancelled job had already cached, and start no new job.
+	if !app.isLargeFile || app.indexJob == nil {
+		t.Fatal("large file is not being indexed in the background")
+	}
+
+	// Progress and completion arrive from the indexing goroutine
+	drainUntil(t, app, func() bool { return app.indexJob == nil })
+	if app.totalLines != lines || app.lineIndex == nil || app.lineIndex.Lines != lines {
+		t.Fatalf("totalLines = %d, want %d", app.totalLines, lines)
+	}
+
+	// Matches arrive in batches from the search goroutine
+	app.find.pattern = regexp.MustCompile(`line \d*7 of`)
+	app.startLargeSearch()
+	s := app.find.large
+	drainUntil(t, app, func() bool { return s.done })
+	if s.total != lines/10 || len(s.lines) != lines/10 {
+		t.Fatalf("found %d matches on %d lines, want %d", s.total, len(s.lines), lines/10)
+	}
+	for i, line := range s.lines {
+		if line%10 != 7 || (i > 0 && line <= s.lines[i-1]) {
+			t.Fatalf("match %d on line %d", i, line)
+		}
+	}
+}
+
+func TestDispatchStaleMessages(t *testing.T) {
+	app := newTestApp(t, map[string]string{"big.log": numberedLines(0, 30000)})
+	selectFile(t, app, "big.log")
+	drainUntil(t, app, func() bool { return app.indexJob == nil })
+	total := app.totalLines
+
+	// Messages from cancelled jobs are ignored
+	var wg sync.WaitGroup
+	old := &indexJob{cancel: make(chan struct{})}
+	for _, msg := range []interface{}{
+		indexProgressMsg{old, 10, 1},
+		indexDoneMsg{old, &lineIndex{Lines: 1}, nil},
+		searchBatchMsg{&largeSearch{}, []int{1}, []int{1}, true},
+		followTickMsg{&followState{}},
+	} {
+		wg.Add(1)
+		go func(msg interface{}) {
+			defer wg.Done()
+			app.events.post(msg)
+		}(msg)
+	}
+	wg.Wait()
+	drainUntil(t, app, func() bool { return true })
+
+	if app.totalLines != total || app.indexJob != nil || app.find.large != nil {
+		t.Fatalf("stale messages changed the app: totalLines %d, want %d", app.totalLines, total)
+	}
+}
+
+func TestDispatchFollow(t *testing.T) {
+	app := newTestApp(t, map[string]string{"app.log": numberedLines(0, 100)})
+	selectFile(t, app, "app.log")
+	if err := app.toggleFollow(app.gui, nil); err != nil {
+		t.Fatal(err)
+	}
+	drainUntil(t, app, func() bool { return app.indexJob == nil })
+	if app.totalLines != 100 {
+		t.Fatalf("totalLines = %d, want 100", app.totalLines)
+	}
+
+	// The file grows while a writer keeps posting ticks
+	f, err := os.OpenFile(filepath.Join(app.notesDir, "app.log"), os.O_APPEND|os.O_WRONLY, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(numberedLines(100, 50)); err != nil {
+		t.Fatal(err)
+	}
+
+	state := app.follow
+	go func() {
+		for i := 0; i < 3; i++ {
+			app.events.post(followTickMsg{state})
+		}
+	}()
+	drainUntil(t, app, func() bool { return app.totalLines == 150 })
+
+	if !state.auto || app.currentLine != app.totalLines-app.viewportHeight {
+		t.Fatalf("viewer at line %d, want the bottom (%d)", app.currentLine, app.totalLines-app.viewportHeight)
+	}
+	if !strings.Contains(app.currentContent, "line 149 of") {
+		t.Errorf("new lines not shown: %q", app.currentContent)
+	}
+	// Truncated and rewritten: the file is indexed again in the background
+	if err := os.WriteFile(filepath.Join(app.notesDir, "app.log"), []byte(numberedLines(0, 60)), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := app.followCheck(); err != nil {
+		t.Fatal(err)
+	}
+	if app.indexJob == nil || app.indexProgress() == "" {
+		t.Fatal("reloaded file not being indexed in the background")
+	}
+	drainUntil(t, app, func() bool { return app.indexJob == nil })
+	if app.totalLines != 60 || app.currentLine != app.totalLines-app.viewportHeight {
+		t.Fatalf("viewer at line %d of %d, want the bottom of 60", app.currentLine, app.totalLines)
+	}
+	if !strings.Contains(app.currentContent, "line 59 of") || strings.Contains(app.currentContent, "line 149 of") {
+		t.Errorf("reloaded file not shown: %q", app.currentContent)
+	}
+}
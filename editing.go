@@ -1,7 +1,10 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
 	"path/filepath"
 	"strings"
 
@@ -14,6 +17,8 @@ import (
 
 // selectItem handles item selection in the sidebar
 func (app *App) selectItem(g *gocui.Gui, v *gocui.View) error {
+	app.endTypeAhead()
+	app.statusNote = ""
 	if len(app.items) == 0 {
 		return nil
 	}
@@ -54,59 +59,18 @@ func (app *App) selectItem(g *gocui.Gui, v *gocui.View) error {
 
 // handleEnterInMainView handles Enter key in main view
 func (app *App) handleEnterInMainView(g *gocui.Gui, v *gocui.View) error {
+	if app.completionShown() {
+		return app.acceptCompletion(v)
+	}
+	if app.isEditMode && app.vimEnabled && app.vim.mode != vimInsert {
+		return app.vimHandleEnter(v)
+	}
+
 	if app.isEditMode {
 		// In edit mode, Enter should add a new line
-		// We need to manually handle this since we've overridden the default behavior
-		cx, cy := v.Cursor()
-		ox, oy := v.Origin()
-
-		// Get current content
-		v.Rewind()
-		buffer := v.ViewBuffer()
-		lines := strings.Split(buffer, "\n")
-
-		// Calculate actual position in the text
-		actualY := cy + oy
-		actualX := cx + ox
-
-		// Insert newline at cursor position
-		if actualY < len(lines) {
-			currentLine := lines[actualY]
-			// Split the current line at cursor position
-			if actualX <= len(currentLine) {
-				before := currentLine[:actualX]
-				after := currentLine[actualX:]
-				lines[actualY] = before
-				// Insert new line after current line
-				newLines := make([]string, 0, len(lines)+1)
-				newLines = append(newLines, lines[:actualY+1]...)
-				newLines = append(newLines, after)
-				newLines = append(newLines, lines[actualY+1:]...)
-				lines = newLines
-			}
-		} else {
-			// At end of file, just add a new line
-			lines = append(lines, "")
-		}
-
-		// Update view content
-		newContent := strings.Join(lines, "\n")
-		v.Clear()
-		fmt.Fprint(v, newContent)
-
-		// Handle cursor positioning and viewport scrolling
-		_, maxY := v.Size()
-		newCursorY := cy + 1
-
-		// If the new cursor position would be beyond the visible area, scroll the view
-		if newCursorY >= maxY {
-			// Scroll the view down to keep cursor visible
-			v.SetOrigin(ox, oy+1)
-			v.SetCursor(0, maxY-1) // Keep cursor at bottom of view
-		} else {
-			// Move cursor to beginning of new line
-			v.SetCursor(0, newCursorY)
-		}
+		// We need to manually handle this since we've overridden the default behavior.
+		// List items continue with the same marker on the new line.
+		app.continueListInView(v)
 		return nil
 	} else {
 		// In view mode, Enter should start editing
@@ -121,25 +85,43 @@ func (app *App) enterEditMode(g *gocui.Gui, v *gocui.View) error {
 	}
 
 	currentItem := app.items[app.currentItem]
-	if currentItem.IsFolder {
-		return nil // Can't edit folders
+	if currentItem.IsFolder || app.binary != nil {
+		return nil // Can't edit folders or binary files
 	}
-
-	// Don't allow editing large files
-	if app.isLargeFile {
-		return nil
+	if app.isLargeFile && app.indexJob != nil {
+		return nil // Large files need their line count before they can be edited
+	}
+	if app.follow != nil {
+		app.stopFollow()
+		app.loadCurrentItem()
 	}
 
 	app.isEditMode = true
 	app.originalContent = app.currentContent // Store original content for change detection
+	app.startEditRecovery(currentItem.Path)
+	app.snippets = app.loadSnippets()
 
 	// Update view properties
 	v.Editable = true
-	v.Clear()
-	fmt.Fprint(v, app.currentContent)
+	v.Editor = app.mainEditor()
+	app.resetVim()
+	if app.isLargeFile {
+		// Large files are edited a window at a time
+		if err := app.enterLargeEditMode(v); err != nil {
+			app.isEditMode = false
+			app.largeEdit = nil
+			return nil
+		}
+	} else {
+		v.Clear()
+		fmt.Fprint(v, app.currentContent)
+	}
 
 	// Set focus to main view for editing
 	g.SetCurrentView(MAIN_VIEW)
+	app.previewDirty = true
+	app.relayoutMainArea(g)
+	app.scheduleSpellCheck()
 
 	app.updateMainView()
 	app.updateStatusBar()
@@ -147,14 +129,57 @@ func (app *App) enterEditMode(g *gocui.Gui, v *gocui.View) error {
 	return nil
 }
 
+// mainEditor returns the editor used for the main view in edit mode
+func (app *App) mainEditor() gocui.Editor {
+	var editor gocui.Editor = gocui.DefaultEditor
+	if app.vimEnabled {
+		editor = gocui.EditorFunc(app.vimEdit)
+	}
+
+	return gocui.EditorFunc(func(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+		// Alt+key shortcuts arrive here instead of through the keybindings
+		if ch != 0 && mod != gocui.ModNone && app.runRuneBinding(MAIN_VIEW, v, ch, mod) {
+			return
+		}
+		editor.Edit(v, key, ch, mod)
+		if ch != 0 || key == gocui.KeySpace {
+			app.expandSnippet(v)
+		}
+		app.refreshCompletion(v)
+		app.schedulePreview()
+		app.scheduleSpellCheck()
+	})
+}
+
+// handleEscInMainView handles Esc in the main view
+func (app *App) handleEscInMainView(g *gocui.Gui, v *gocui.View) error {
+	if app.complete.active {
+		app.closeCompletion()
+		return nil
+	}
+	if app.snippet.active() {
+		app.snippet = snippetSession{} // Leave the remaining tab stops
+		return nil
+	}
+
+	if app.isEditMode && app.vimEnabled {
+		// In Vim mode Esc returns to normal mode; :q leaves edit mode
+		app.vimEscape(v)
+		return nil
+	}
+	return app.exitEditMode(g, v)
+}
+
 // hasUnsavedChanges checks if there are unsaved changes
 func (app *App) hasUnsavedChanges(v *gocui.View) bool {
 	if !app.isEditMode {
 		return false
 	}
+	if app.largeEdit != nil {
+		return app.largeEditModified(v)
+	}
 
-	v.Rewind()
-	currentContent := v.ViewBuffer()
+	currentContent := v.Buffer()
 	return currentContent != app.originalContent
 }
 
@@ -188,10 +213,29 @@ func (app *App) exitEditMode(g *gocui.Gui, v *gocui.View) error {
 func (app *App) doExitEditMode(g *gocui.Gui, v *gocui.View) error {
 	app.isEditMode = false
 	v.Editable = false
+	app.closeCompletion()
+	app.snippet = snippetSession{}
+	app.relayoutMainArea(g)
+
+	// Changes were either saved or declined, so nothing is left to recover
+	app.removeSwap()
+	app.editPath = ""
 
 	// Update content from view
-	v.Rewind()
-	app.currentContent = v.ViewBuffer()
+	if app.largeEdit != nil {
+		// Unsaved edits are dropped; view the file from where the cursor was
+		app.currentLine = app.largeEditLine(v)
+		if app.currentLine >= app.totalLines {
+			app.currentLine = app.totalLines - 1
+		}
+		if app.currentLine < 0 {
+			app.currentLine = 0
+		}
+		app.largeEdit = nil
+		app.currentContent, _ = app.getViewportContent()
+	} else {
+		app.currentContent = v.Buffer()
+	}
 
 	app.updateMainView()
 	app.updateStatusBar()
@@ -210,14 +254,24 @@ func (app *App) saveNote(g *gocui.Gui, v *gocui.View) error {
 		return nil // Can't save folders
 	}
 
-	// Don't allow saving large files in this mode
-	if app.isLargeFile {
+	// Large files are streamed back to disk from the piece table
+	if app.largeEdit != nil {
+		if err := app.saveLargeFile(v, filepath.Join(app.notesDir, currentItem.Path)); err != nil {
+			return err
+		}
+		app.removeSwap()
+		app.refreshVaultLists()
+		if head, err := app.readNoteHead(filepath.Join(app.notesDir, currentItem.Path)); err == nil {
+			app.updateNoteTitle(currentItem.Name, head)
+		}
+		app.updateSidebar()
+		app.updateHeader()
+		app.updateStatusBar()
 		return nil
 	}
 
 	// Get content from view
-	v.Rewind()
-	content := v.ViewBuffer()
+	content := v.Buffer()
 
 	// Save to file
 	notePath := filepath.Join(app.notesDir, currentItem.Path)
@@ -228,19 +282,11 @@ func (app *App) saveNote(g *gocui.Gui, v *gocui.View) error {
 	// Update current content and reset original content
 	app.currentContent = content
 	app.originalContent = content
+	app.removeSwap()
 
 	// Update title if it changed
-	newTitle := app.extractTitleFromContent(content)
-	if newTitle != "" {
-		app.noteTitles[currentItem.Name] = newTitle
-		// Update the item title in the list
-		for i := range app.items {
-			if app.items[i].Name == currentItem.Name {
-				app.items[i].Title = "📄 " + newTitle
-				break
-			}
-		}
-	}
+	app.updateNoteTitle(currentItem.Name, content)
+	app.refreshVaultLists()
 
 	app.updateSidebar()
 	app.updateHeader()
@@ -249,6 +295,110 @@ func (app *App) saveNote(g *gocui.Gui, v *gocui.View) error {
 	return nil
 }
 
+// updateNoteTitle refreshes a note's title in noteTitles and the sidebar items
+func (app *App) updateNoteTitle(name, content string) {
+	newTitle := app.extractTitleFromContent(content)
+	if newTitle == "" {
+		return
+	}
+
+	app.noteTitles[name] = newTitle
+	// Update the item title in the list
+	for i := range app.items {
+		if app.items[i].Name == name {
+			app.items[i].Title = "📄 " + newTitle
+			break
+		}
+	}
+}
+
+// =============================================================================
+// EXTERNAL EDITOR
+// =============================================================================
+
+// errExternalEdit is returned from the main loop to suspend the GUI for an external editor
+var errExternalEdit = errors.New("suspend for external editor")
+
+// editInExternalEditor opens the current note in $VISUAL or $EDITOR
+func (app *App) editInExternalEditor(g *gocui.Gui, v *gocui.View) error {
+	if len(app.items) == 0 {
+		return nil
+	}
+
+	currentItem := app.items[app.currentItem]
+	if currentItem.IsFolder {
+		return nil // Can't edit folders
+	}
+
+	// Keep in-app edits before handing the file over
+	if app.isEditMode {
+		mainView, err := g.View(MAIN_VIEW)
+		if err != nil {
+			return nil
+		}
+		if app.hasUnsavedChanges(mainView) {
+			if err := app.saveNote(g, mainView); err != nil {
+				return err
+			}
+		}
+		if err := app.doExitEditMode(g, mainView); err != nil {
+			return err
+		}
+	}
+
+	// Resolve the note's real path so the editor writes through symlinks
+	notePath, err := filepath.Abs(filepath.Join(app.notesDir, currentItem.Path))
+	if err != nil {
+		return nil
+	}
+	if realPath, err := filepath.EvalSymlinks(notePath); err == nil {
+		notePath = realPath
+	}
+
+	app.externalEditPath = notePath
+	return errExternalEdit
+}
+
+// runExternalEditor runs the editor on the pending note while the GUI is closed,
+// then reloads the note
+func (app *App) runExternalEditor() {
+	notePath := app.externalEditPath
+	app.externalEditPath = ""
+	if notePath == "" {
+		return
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+
+	// $EDITOR may carry arguments, e.g. "code --wait"
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{"vi"} // Set but blank
+	}
+	cmd := exec.Command(args[0], append(args[1:], notePath)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		app.statusNote = fmt.Sprintf("%s failed: %v", args[0], err)
+	}
+
+	// Reload the note: it may have changed size or title
+	app.closeFile()
+	if app.currentItem < len(app.items) {
+		if content, err := app.readNoteHead(notePath); err == nil {
+			app.updateNoteTitle(app.items[app.currentItem].Name, content)
+		}
+	}
+	app.resumeView = MAIN_VIEW
+}
+
 // =============================================================================
 // CLIPBOARD HANDLERS
 // =============================================================================
@@ -260,9 +410,7 @@ func (app *App) copySelection(g *gocui.Gui, v *gocui.View) error {
 	}
 
 	// Get the current selection or current line
-	v.Rewind()
-	text := v.ViewBuffer()
-	lines := strings.Split(text, "\n")
+	lines := v.BufferLines()
 
 	// For now, copy the current line (simple implementation)
 	// TODO: Implement proper text selection
@@ -295,32 +443,52 @@ func (app *App) pasteClipboard(g *gocui.Gui, v *gocui.View) error {
 		return nil
 	}
 
-	// Insert the pasted text at cursor position
-	cx, cy := v.Cursor()
-	ox, _ := v.Origin()
+	app.insertAtCursor(v, text)
+	return nil
+}
 
-	// Get current line content
-	v.Rewind()
-	buffer := v.ViewBuffer()
-	lines := strings.Split(buffer, "\n")
+// insertAtCursor inserts text, which may span lines, at the edit cursor
+func (app *App) insertAtCursor(v *gocui.View, text string) {
+	lines, x, y := editBufferLines(v)
+	line := []rune(lines[y])
+	if x > len(line) {
+		x = len(line)
+	}
+	before, after := string(line[:x]), string(line[x:])
 
-	if cy >= 0 && cy < len(lines) {
-		currentLine := lines[cy]
-		// Insert at cursor position
-		before := currentLine[:cx+ox] // Adjust for origin
-		after := currentLine[cx+ox:]
-		newLine := before + text + after
-		lines[cy] = newLine
-
-		// Update the view buffer
-		newContent := strings.Join(lines, "\n")
-		v.Clear()
-		fmt.Fprint(v, newContent)
+	inserted := strings.Split(text, "\n")
+	last := len(inserted) - 1
+	cursorX := len([]rune(inserted[last]))
+	if last == 0 {
+		cursorX += x
+	}
+	inserted[0] = before + inserted[0]
+	inserted[last] += after
 
-		// Move cursor to after pasted text
-		newCursorX := cx + len(text)
-		v.SetCursor(newCursorX, cy)
+	lines = append(lines[:y], append(inserted, lines[y+1:]...)...)
+	app.setEditLinesAt(v, lines, cursorX, y+last)
+}
+
+// clipboardHistory picks one of the recent copies to paste
+func (app *App) clipboardHistory(g *gocui.Gui, v *gocui.View) error {
+	if !app.isEditMode || len(app.clipboard.history) == 0 {
+		return nil
 	}
 
-	return nil
+	items := make([]pickerItem, len(app.clipboard.history))
+	for i, text := range app.clipboard.history {
+		items[i] = pickerItem{label: clipboardPreview(text), value: text}
+	}
+	return app.openPicker(" Clipboard History ", items, func(item *pickerItem, query string) error {
+		if item == nil {
+			return nil
+		}
+		mainView, err := g.View(MAIN_VIEW)
+		if err != nil {
+			return nil
+		}
+		app.clipboard.remember(item.value) // Picked entries move to the top
+		app.insertAtCursor(mainView, item.value)
+		return nil
+	})
 }
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRunExternalEditor(t *testing.T) {
+	// A stand-in vi that fails, found through PATH
+	bin := t.TempDir()
+	if err := os.WriteFile(filepath.Join(bin, "vi"), []byte("#!/bin/sh\nexit 3\n"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	app := newTestApp(t, map[string]string{"note.md": "# Note\n"})
+	notePath := filepath.Join(app.notesDir, "note.md")
+
+	t.Setenv("VISUAL", " \t ")
+	app.externalEditPath = notePath
+	app.runExternalEditor()
+	if !strings.Contains(app.statusNote, "vi failed: exit status 3") {
+		t.Errorf("status note = %q, want the failed vi", app.statusNote)
+	}
+
+	// The list may be emptied while the editor runs
+	t.Setenv("VISUAL", "/bin/sh -c true")
+	app.statusNote = ""
+	app.items = nil
+	app.externalEditPath = notePath
+	app.runExternalEditor()
+	if app.statusNote != "" || app.resumeView != MAIN_VIEW {
+		t.Errorf("status note %q, resume view %q after a clean run", app.statusNote, app.resumeView)
+	}
+}
+
+func TestSaveNoteWideLine(t *testing.T) {
+	note := "# Wide\n" + strings.Repeat("word ", 40) + "end\nlast\n"
+	app := newTestApp(t, map[string]string{"wide.md": note})
+	selectFile(t, app, "wide.md")
+
+	v, err := app.gui.View(MAIN_VIEW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.enterEditMode(app.gui, v); err != nil {
+		t.Fatal(err)
+	}
+	if width, _ := v.Size(); width >= len(note) {
+		t.Fatalf("view is %d wide, want the note's line to wrap", width)
+	}
+
+	// Wrapping the line on screen is not an edit
+	if app.hasUnsavedChanges(v) {
+		t.Error("unedited note with a wrapped line reported as modified")
+	}
+	if _, modified := app.swapBuffer(v); modified {
+		t.Error("unedited note with a wrapped line needs a swap file")
+	}
+
+	if err := app.saveNote(app.gui, v); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(app.notesDir, "wide.md"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != note {
+		t.Errorf("saved %q, want the note unchanged", data)
+	}
+	if app.hasUnsavedChanges(v) {
+		t.Error("note reported as modified after saving")
+	}
+}
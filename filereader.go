@@ -0,0 +1,158 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+	"os"
+)
+
+// =============================================================================
+// LARGE FILE READERS
+// =============================================================================
+
+// largeFile is an open large file. Lines are read from byte offsets, which the
+// line index provides.
+type largeFile interface {
+	io.ReaderAt
+	io.Closer
+	Name() string
+	lines(offset int64) lineReader
+}
+
+// lineReader reads consecutive lines of a large file
+type lineReader interface {
+	// segment reads the next line and returns at most max bytes of it from
+	// byte from on (max < 0 keeps the rest), and whether the line goes on
+	segment(from, max int) (string, bool, error)
+}
+
+// openLargeFile opens a file for reading, memory-mapped when mapped is set
+// and the platform supports it, through the file otherwise
+func openLargeFile(path string, mapped bool) (largeFile, error) {
+	if mapped {
+		if file, err := openMappedFile(path); err == nil {
+			return file, nil
+		}
+	}
+
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	return &seekFile{file}, nil
+}
+
+// seekFile reads lines by seeking the file and buffering from there
+type seekFile struct {
+	*os.File
+}
+
+// lines seeks to offset and reads lines from there
+func (f *seekFile) lines(offset int64) lineReader {
+	f.Seek(offset, io.SeekStart)
+	return &bufferedLines{bufio.NewReaderSize(f.File, DEFAULT_CHUNK_SIZE)}
+}
+
+// bufferedLines reads lines through a bufio.Reader
+type bufferedLines struct {
+	r *bufio.Reader
+}
+
+// segment reads the next line
+func (b *bufferedLines) segment(from, max int) (string, bool, error) {
+	return readLineSegment(b.r, from, max)
+}
+
+// mappedFile is a file mapped into memory; lines are sliced straight out of it
+type mappedFile struct {
+	name string
+	data []byte
+}
+
+// Name returns the path the file was opened with
+func (m *mappedFile) Name() string {
+	return m.name
+}
+
+// ReadAt copies bytes out of the mapping
+func (m *mappedFile) ReadAt(p []byte, off int64) (int, error) {
+	if off >= int64(len(m.data)) {
+		return 0, io.EOF
+	}
+	n := copy(p, m.data[off:])
+	if n < len(p) {
+		return n, io.EOF
+	}
+	return n, nil
+}
+
+// lines reads lines from offset on
+func (m *mappedFile) lines(offset int64) lineReader {
+	return &mappedLines{data: m.data, pos: int(offset)}
+}
+
+// mappedLines reads lines out of a mapping
+type mappedLines struct {
+	data []byte
+	pos  int
+}
+
+// segment reads the next line, like readLineSegment does from a reader
+func (m *mappedLines) segment(from, max int) (string, bool, error) {
+	if m.pos >= len(m.data) {
+		return "", false, io.EOF
+	}
+
+	line := m.data[m.pos:]
+	if i := bytes.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+		m.pos += i + 1
+		if len(line) > 0 && line[len(line)-1] == '\r' {
+			line = line[:len(line)-1] // CRLF line ending
+		}
+	} else {
+		m.pos = len(m.data)
+	}
+
+	if from > len(line) {
+		from = len(line)
+	}
+	end := len(line)
+	if max >= 0 && from+max < end {
+		end = from + max
+	}
+	return string(line[from:end]), end < len(line), nil
+}
+
+// fileChunks returns a function reading the bytes [offset, size) of r a chunk
+// at a time. Mapped files hand out slices of the mapping instead of copying.
+func fileChunks(r io.ReaderAt, offset, size int64) func() ([]byte, error) {
+	if m, ok := r.(*mappedFile); ok {
+		if size > int64(len(m.data)) {
+			size = int64(len(m.data))
+		}
+		return func() ([]byte, error) {
+			if offset >= size {
+				return nil, io.EOF
+			}
+			end := offset + DEFAULT_CHUNK_SIZE
+			if end > size {
+				end = size
+			}
+			chunk := m.data[offset:end]
+			offset = end
+			return chunk, nil
+		}
+	}
+
+	reader := io.NewSectionReader(r, offset, size-offset)
+	buf := make([]byte, DEFAULT_CHUNK_SIZE)
+	return func() ([]byte, error) {
+		n, err := reader.Read(buf)
+		if n > 0 {
+			return buf[:n], nil
+		}
+		return nil, err
+	}
+}
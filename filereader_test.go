@@ -0,0 +1,215 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// readerFixtures are files both large-file readers must read the same way
+var readerFixtures = []struct {
+	name    string
+	content string
+}{
+	{"empty", ""},
+	{"no trailing newline", "first\nsecond\nthird € without an end"},
+	{"crlf", "one\r\ntwo\r\n\r\nthree € four\r\nlast\r\n"},
+	{"lone carriage returns", "a\rb\r\nc\r"},
+	{"long lines", "short\n" + strings.Repeat("abcdefghij", 3*DEFAULT_CHUNK_SIZE/10) + " match\n" +
+		strings.Repeat("z", DEFAULT_CHUNK_SIZE) + "\nend match\n"},
+	{"runes across chunks", runesAcrossChunks()},
+	{"many lines", manyLines(2*LINE_INDEX_INTERVAL + 345)},
+}
+
+// runesAcrossChunks returns lines whose multibyte runes straddle the chunk and
+// segment boundaries
+func runesAcrossChunks() string {
+	var b strings.Builder
+	for _, size := range []int{LINE_SEGMENT_BYTES, DEFAULT_CHUNK_SIZE} {
+		for shift := 1; shift <= 3; shift++ {
+			b.WriteString(strings.Repeat("x", size-shift))
+			b.WriteString(strings.Repeat("€🙂", 4) + " match\n")
+		}
+	}
+	b.WriteString(strings.Repeat("é", DEFAULT_CHUNK_SIZE)) // Unterminated
+	return b.String()
+}
+
+// manyLines returns n lines, every seventh holding a match, with some
+// multibyte text and CRLF endings
+func manyLines(n int) string {
+	var b strings.Builder
+	for i := 0; i < n; i++ {
+		b.WriteString(strings.Repeat("ü", i%50))
+		if i%7 == 0 {
+			b.WriteString(" match")
+		}
+		if i%3 == 0 {
+			b.WriteString("\r")
+		}
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// expectedLines splits content the way bufio.ScanLines does
+func expectedLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	for i, line := range lines {
+		if i < len(lines)-1 || strings.HasSuffix(content, "\n") {
+			lines[i] = strings.TrimSuffix(line, "\r")
+		}
+	}
+	return lines
+}
+
+// readSegments reads every line of a reader as segments of max bytes from from
+func readSegments(t *testing.T, r lineReader, from, max int) ([]string, []bool) {
+	t.Helper()
+	var segments []string
+	var more []bool
+	for {
+		segment, cut, err := r.segment(from, max)
+		if err == io.EOF {
+			return segments, more
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		segments = append(segments, segment)
+		more = append(more, cut)
+	}
+}
+
+func TestLargeFileReaders(t *testing.T) {
+	for _, fx := range readerFixtures {
+		t.Run(fx.name, func(t *testing.T) {
+			dir := t.TempDir()
+			path := filepath.Join(dir, "fixture.txt")
+			if err := os.WriteFile(path, []byte(fx.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			want := expectedLines(fx.content)
+			app := newTestApp(t, nil)
+
+			type result struct {
+				whole, window []string
+				cut           []bool
+				index         *lineIndex
+				fromOffsets   []string
+				hits          []int
+			}
+			results := map[bool]result{}
+
+			for _, mapped := range []bool{true, false} {
+				file, err := openLargeFile(path, mapped)
+				if err != nil {
+					t.Fatal(err)
+				}
+				var r result
+
+				// Whole lines, and the window the viewer shows when scrolled sideways
+				r.whole, _ = readSegments(t, file.lines(0), 0, -1)
+				r.window, r.cut = readSegments(t, file.lines(0), LINE_SEGMENT_BYTES/2, LINE_SEGMENT_BYTES)
+
+				// The line index, and reading from each of its offsets
+				r.index, err = buildLineIndex(path, info, mapped, nil, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, offset := range r.index.Offsets {
+					line, _, err := file.lines(offset).segment(0, -1)
+					if err != nil && err != io.EOF {
+						t.Fatal(err)
+					}
+					r.fromOffsets = append(r.fromOffsets, line)
+				}
+				file.Close()
+
+				// Search hits from the background scan
+				r.hits = searchHits(t, app, path, mapped)
+				results[mapped] = r
+			}
+
+			mapped, seek := results[true], results[false]
+			if !reflect.DeepEqual(seek.whole, want) {
+				t.Errorf("seek reader lines differ from the file's lines")
+			}
+			if !reflect.DeepEqual(mapped.whole, seek.whole) {
+				t.Errorf("whole lines differ between mapped and seek readers")
+			}
+			if !reflect.DeepEqual(mapped.window, seek.window) || !reflect.DeepEqual(mapped.cut, seek.cut) {
+				t.Errorf("line segments differ between mapped and seek readers")
+			}
+			for i, line := range want {
+				lo, hi := LINE_SEGMENT_BYTES/2, LINE_SEGMENT_BYTES/2+LINE_SEGMENT_BYTES
+				if lo > len(line) {
+					lo = len(line)
+				}
+				if hi > len(line) {
+					hi = len(line)
+				}
+				if seek.window[i] != line[lo:hi] || seek.cut[i] != (hi < len(line)) {
+					t.Fatalf("line %d: segment %q (cut %v), want %q", i, seek.window[i], seek.cut[i], line[lo:hi])
+				}
+			}
+
+			if mapped.index.Lines != len(want) || seek.index.Lines != len(want) {
+				t.Errorf("indexed %d (mapped) and %d (seek) lines, want %d", mapped.index.Lines, seek.index.Lines, len(want))
+			}
+			if !reflect.DeepEqual(mapped.index.Offsets, seek.index.Offsets) {
+				t.Errorf("index offsets differ: %v and %v", mapped.index.Offsets, seek.index.Offsets)
+			}
+			for k, line := range seek.fromOffsets {
+				n, wantLine := k*LINE_INDEX_INTERVAL, "" // An empty file has the single offset 0
+				if n < len(want) {
+					wantLine = want[n]
+				}
+				if line != wantLine || mapped.fromOffsets[k] != line {
+					t.Errorf("offset %d reads %q, want line %d %q", k, line, n, wantLine)
+				}
+			}
+
+			var wantHits []int
+			for i, line := range want {
+				if strings.Contains(line, "match") {
+					wantHits = append(wantHits, i)
+				}
+			}
+			if !reflect.DeepEqual(mapped.hits, wantHits) || !reflect.DeepEqual(seek.hits, wantHits) {
+				t.Errorf("search hits %v (mapped) and %v (seek), want %v", mapped.hits, seek.hits, wantHits)
+			}
+		})
+	}
+}
+
+// searchHits runs the background large-file search over path and returns the
+// lines with matches
+func searchHits(t *testing.T, app *App, path string, mapped bool) []int {
+	t.Helper()
+	app.mapFiles = mapped
+	file, err := openLargeFile(path, mapped)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+
+	app.fileHandle = file
+	app.find.pattern = regexp.MustCompile(`match`)
+	app.startLargeSearch()
+	s := app.find.large
+	drainUntil(t, app, func() bool { return s.done })
+	app.fileHandle = nil
+	return s.lines
+}
@@ -1,7 +1,7 @@
 package main
 
 import (
-	"bufio"
+	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
@@ -46,9 +46,9 @@ func (app *App) loadItems() {
 		} else if strings.HasSuffix(file.Name(), ".md") || strings.HasSuffix(file.Name(), ".txt") {
 			// Load content to extract title
 			filePath := filepath.Join(currentDir, file.Name())
-			content, err := ioutil.ReadFile(filePath)
+			content, err := app.readNoteHead(filePath)
 			if err == nil {
-				title := app.extractTitleFromContent(string(content))
+				title := app.extractTitleFromContent(content)
 				if title != "" {
 					item.Title = "📄 " + title
 					app.noteTitles[file.Name()] = title
@@ -103,11 +103,7 @@ func (app *App) loadItems() {
 			"- ^^Large text effect^^\n" +
 			"- *Italic* and `code` formatting\n\n" +
 			"### 🚀 Navigation\n" +
-			"- `Tab`: Switch panels\n" +
-			"- `Enter`: Open file/folder or edit\n" +
-			"- `Ctrl+N`: New note\n" +
-			"- `Ctrl+F`: New folder\n" +
-			"- `d`: Delete item\n\n" +
+			app.welcomeKeyHints() + "\n" +
 			"*Try creating folders and organizing your notes!*"
 
 		// Create welcome note as .md file
@@ -127,6 +123,25 @@ func (app *App) loadItems() {
 	// Views will be updated in the layout function
 }
 
+// welcomeKeyHints lists the main navigation keys for the welcome note
+func (app *App) welcomeKeyHints() string {
+	hints := []keyHintSpec{
+		{GLOBAL_SCOPE, []string{"toggle_sidebar"}, "Switch panels"},
+		{SIDEBAR_VIEW, []string{"select_item"}, "Open file/folder or edit"},
+		{GLOBAL_SCOPE, []string{"new_note"}, "New note"},
+		{GLOBAL_SCOPE, []string{"new_folder"}, "New folder"},
+		{SIDEBAR_VIEW, []string{"delete_item"}, "Delete item"},
+	}
+
+	var b strings.Builder
+	for _, hint := range hints {
+		if keys := app.keyHint(hint.scope, hint.actions[0]); keys != "" {
+			b.WriteString("- `" + keys + "`: " + hint.label + "\n")
+		}
+	}
+	return b.String()
+}
+
 // loadCurrentItem loads the content of the currently selected item
 func (app *App) loadCurrentItem() {
 	if len(app.items) == 0 {
@@ -142,6 +157,7 @@ func (app *App) loadCurrentItem() {
 
 	// Only load content for files, not folders
 	if currentItem.IsFolder {
+		app.binary = nil
 		app.currentContent = ""
 		app.isLargeFile = false
 		app.closeFile()
@@ -152,6 +168,14 @@ func (app *App) loadCurrentItem() {
 
 	filePath := filepath.Join(app.notesDir, currentItem.Path)
 
+	// Binary files get a description instead of their bytes
+	if app.loadBinaryFile(filePath) {
+		app.updateMainView()
+		app.updateStatusBar()
+		return
+	}
+	app.binary = nil
+
 	// Check file size and determine if it's a large file
 	if err := app.checkFileSize(filePath); err != nil {
 		app.currentContent = ""
@@ -192,6 +216,21 @@ func (app *App) saveNoteContent(notePath, content string) error {
 	return ioutil.WriteFile(notePath, []byte(content), 0644)
 }
 
+// readNoteHead reads the beginning of a note, enough to extract its title
+func (app *App) readNoteHead(notePath string) (string, error) {
+	file, err := os.Open(notePath)
+	if err != nil {
+		return "", err
+	}
+	defer file.Close()
+
+	head, err := ioutil.ReadAll(io.LimitReader(file, int64(app.chunkSize)))
+	if err != nil {
+		return "", err
+	}
+	return string(head), nil
+}
+
 // extractTitleFromContent extracts the title from markdown content
 func (app *App) extractTitleFromContent(content string) string {
 	lines := strings.Split(content, "\n")
@@ -223,6 +262,51 @@ func (app *App) getDisplayTitle(filename string) string {
 	return strings.TrimSuffix(filename, filepath.Ext(filename))
 }
 
+// vaultNote is a note anywhere in the vault
+type vaultNote struct {
+	Path  string // relative to notesDir
+	Title string
+}
+
+// vaultNotes lists every note in the vault with its title, sorted by title
+func (app *App) vaultNotes() []vaultNote {
+	var notes []vaultNote
+	filepath.Walk(app.notesDir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return nil
+		}
+		if info.IsDir() {
+			if path != app.notesDir && strings.HasPrefix(info.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !strings.HasSuffix(info.Name(), ".md") && !strings.HasSuffix(info.Name(), ".txt") {
+			return nil
+		}
+
+		rel, err := filepath.Rel(app.notesDir, path)
+		if err != nil {
+			return nil
+		}
+		title := ""
+		if content, err := app.readNoteHead(path); err == nil {
+			title = app.extractTitleFromContent(content)
+		}
+		if title == "" {
+			title = strings.TrimSuffix(info.Name(), filepath.Ext(info.Name()))
+		}
+
+		notes = append(notes, vaultNote{Path: rel, Title: title})
+		return nil
+	})
+
+	sort.Slice(notes, func(i, j int) bool {
+		return strings.ToLower(notes[i].Title) < strings.ToLower(notes[j].Title)
+	})
+	return notes
+}
+
 // sanitizeFilename removes invalid characters from filenames
 func (app *App) sanitizeFilename(name string) string {
 	// Remove invalid characters for filenames
@@ -251,20 +335,26 @@ func (app *App) checkFileSize(filePath string) error {
 		return err
 	}
 
+	app.cancelLineIndexing()
+	app.cancelLargeSearch()
+	if app.follow != nil && app.follow.path != filePath {
+		app.stopFollow()
+	}
 	app.fileSize = fileInfo.Size()
-	app.isLargeFile = app.fileSize > LARGE_FILE_THRESHOLD
+	app.isLargeFile = app.fileSize > LARGE_FILE_THRESHOLD || app.follow != nil
 
 	if app.isLargeFile {
 		app.totalChunks = int((app.fileSize + int64(app.chunkSize) - 1) / int64(app.chunkSize))
 		app.currentChunk = 0
 		app.currentLine = 0
+		app.hscroll = 0
 		app.viewportHeight = DEFAULT_VIEWPORT
 		app.lineCache = nil
 		app.cacheStartLine = -1
 		app.cacheEndLine = -1
 
-		// Count total lines in file
-		if err := app.countTotalLines(filePath); err != nil {
+		// Count total lines in file, in the background unless already indexed
+		if err := app.startLineIndexing(filePath); err != nil {
 			return err
 		}
 	}
@@ -272,29 +362,11 @@ func (app *App) checkFileSize(filePath string) error {
 	return nil
 }
 
-// countTotalLines counts the total number of lines in a file
-func (app *App) countTotalLines(filePath string) error {
-	file, err := os.Open(filePath)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
-	lineCount := 0
-	scanner := bufio.NewScanner(file)
-	for scanner.Scan() {
-		lineCount++
-	}
-
-	app.totalLines = lineCount
-	return scanner.Err()
-}
-
 // openFileForReading opens a file for large file reading
 func (app *App) openFileForReading(filePath string) error {
 	app.closeFile() // Close any existing file handle
 
-	file, err := os.Open(filePath)
+	file, err := openLargeFile(filePath, app.useMapping())
 	if err != nil {
 		return err
 	}
@@ -303,6 +375,12 @@ func (app *App) openFileForReading(filePath string) error {
 	return nil
 }
 
+// useMapping reports whether large files should be memory-mapped. Followed
+// files are not, as a file truncated under its mapping can't be read safely.
+func (app *App) useMapping() bool {
+	return app.mapFiles && app.follow == nil
+}
+
 // closeFile closes the current file handle
 func (app *App) closeFile() {
 	if app.fileHandle != nil {
@@ -0,0 +1,522 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// =============================================================================
+// FIND AND REPLACE
+// =============================================================================
+
+// findMatch is one match of the find query (rune columns within a line)
+type findMatch struct {
+	line, start, end int
+}
+
+// findState holds the find bar state
+type findState struct {
+	active        bool
+	query         string // last query, restored when the find bar reopens
+	replacement   string // last replacement text
+	caseSensitive bool
+	wholeWord     bool
+	regex         bool
+	pattern       *regexp.Regexp
+	matches       []findMatch
+	current       int          // index into matches, -1 if none
+	anchor        findMatch    // position the search starts from
+	largeLine     int          // line of the current match in a large file, -1 if none
+	large         *largeSearch // background scan of a large file
+	message       string       // status shown in the find bar subtitle
+}
+
+// compile builds the search pattern from the query and toggles
+func (f *findState) compile() error {
+	f.pattern = nil
+	if f.query == "" {
+		return nil
+	}
+
+	expr := f.query
+	if !f.regex {
+		expr = regexp.QuoteMeta(expr)
+	}
+	if f.wholeWord {
+		expr = `\b(?:` + expr + `)\b`
+	}
+	if !f.caseSensitive {
+		expr = "(?i)" + expr
+	}
+
+	re, err := regexp.Compile(expr)
+	if err != nil {
+		return err
+	}
+	f.pattern = re
+	return nil
+}
+
+// findMatchesInLines returns every non-empty match of re; firstLine numbers lines[0]
+func findMatchesInLines(lines []string, firstLine int, re *regexp.Regexp) []findMatch {
+	var matches []findMatch
+	for i, line := range lines {
+		for _, loc := range re.FindAllStringIndex(line, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			matches = append(matches, findMatch{
+				line:  firstLine + i,
+				start: utf8.RuneCountInString(line[:loc[0]]),
+				end:   utf8.RuneCountInString(line[:loc[1]]),
+			})
+		}
+	}
+	return matches
+}
+
+// =============================================================================
+// FIND BAR LAYOUT
+// =============================================================================
+
+// layoutFindBar places the find and replace fields along the bottom of the main view
+func (app *App) layoutFindBar(g *gocui.Gui) error {
+	x0, _, x1, y1, err := g.ViewPosition(MAIN_VIEW)
+	if err != nil {
+		// Main view is hidden (small screen), nothing to attach to
+		g.DeleteView(FIND_VIEW)
+		g.DeleteView(REPLACE_VIEW)
+		return nil
+	}
+
+	mid := x0 + (x1-x0)*3/5
+	if v, err := g.SetView(FIND_VIEW, x0+1, y1-3, mid, y1-1, 0); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Editable = true
+		v.Editor = gocui.EditorFunc(app.findEdit)
+		v.KeybindOnEdit = true // Alt+key toggles must fire while typing
+		fmt.Fprint(v, app.find.query)
+		v.SetCursor(utf8.RuneCountInString(app.find.query), 0)
+	}
+	if v, err := g.SetView(REPLACE_VIEW, mid+1, y1-3, x1-1, y1-1, 0); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Editable = true
+		v.KeybindOnEdit = true
+		fmt.Fprint(v, app.find.replacement)
+		v.SetCursor(utf8.RuneCountInString(app.find.replacement), 0)
+	}
+
+	app.updateFindTitles()
+	return nil
+}
+
+// updateFindTitles shows the toggles, match count and replace keys in the find bar
+func (app *App) updateFindTitles() {
+	f := &app.find
+	toggle := func(action, label string, on bool) keyHintSpec {
+		if on {
+			label += " ✓"
+		}
+		return keyHintSpec{FIND_VIEW, []string{action}, label}
+	}
+
+	if v, err := app.gui.View(FIND_VIEW); err == nil {
+		v.Title = " Find - " + app.formatKeyHints(", ",
+			toggle("toggle_case", "Case", f.caseSensitive),
+			toggle("toggle_word", "Word", f.wholeWord),
+			toggle("toggle_regex", "Regex", f.regex)) + " "
+
+		status := f.message
+		if status == "" && f.large != nil {
+			status = f.large.status(f.largeLine)
+		} else if status == "" && f.pattern != nil && !app.isLargeFile {
+			if len(f.matches) == 0 {
+				status = "No matches"
+			} else {
+				status = fmt.Sprintf("%d/%d", f.current+1, len(f.matches))
+			}
+		}
+		v.Subtitle = ""
+		if status != "" {
+			v.Subtitle = " " + status + " "
+		}
+	}
+
+	if v, err := app.gui.View(REPLACE_VIEW); err == nil {
+		v.Title = " Replace - " + app.formatKeyHints(", ",
+			keyHintSpec{FIND_VIEW, []string{"replace_one"}, "One"},
+			keyHintSpec{FIND_VIEW, []string{"replace_all"}, "All"}) + " "
+	}
+}
+
+// =============================================================================
+// FIND HANDLERS
+// =============================================================================
+
+// openFind opens the find bar for the current note
+func (app *App) openFind(g *gocui.Gui, v *gocui.View) error {
+	if len(app.items) == 0 || app.items[app.currentItem].IsFolder || app.binary != nil {
+		return nil
+	}
+
+	f := &app.find
+	f.active = true
+	f.current = -1
+	f.largeLine = -1
+	f.anchor = findMatch{}
+	if app.isEditMode {
+		cx, cy := v.Cursor()
+		f.anchor = findMatch{line: cy, start: cx}
+	}
+
+	if err := app.layoutFindBar(g); err != nil {
+		return err
+	}
+	if _, err := g.SetCurrentView(FIND_VIEW); err != nil {
+		return nil
+	}
+
+	app.runFind()
+	return nil
+}
+
+// closeFind closes the find bar and removes the match highlighting
+func (app *App) closeFind(g *gocui.Gui, v *gocui.View) error {
+	app.readFindFields()
+	app.find.active = false
+	app.find.matches = nil
+	app.cancelLargeSearch()
+
+	g.DeleteView(FIND_VIEW)
+	g.DeleteView(REPLACE_VIEW)
+	g.SetCurrentView(MAIN_VIEW)
+
+	app.repaintFindHighlights()
+	app.updateStatusBar()
+	return nil
+}
+
+// findEdit is the editor for the find field; it searches as you type
+func (app *App) findEdit(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	gocui.DefaultEditor.Edit(v, key, ch, mod)
+	app.runFind()
+}
+
+// findNext jumps to the next match
+func (app *App) findNext(g *gocui.Gui, v *gocui.View) error {
+	return app.findStep(1)
+}
+
+// findPrev jumps to the previous match
+func (app *App) findPrev(g *gocui.Gui, v *gocui.View) error {
+	return app.findStep(-1)
+}
+
+// findToggleCase toggles case-sensitive matching
+func (app *App) findToggleCase(g *gocui.Gui, v *gocui.View) error {
+	app.find.caseSensitive = !app.find.caseSensitive
+	app.runFind()
+	return nil
+}
+
+// findToggleWord toggles whole-word matching
+func (app *App) findToggleWord(g *gocui.Gui, v *gocui.View) error {
+	app.find.wholeWord = !app.find.wholeWord
+	app.runFind()
+	return nil
+}
+
+// findToggleRegex toggles regular expression matching
+func (app *App) findToggleRegex(g *gocui.Gui, v *gocui.View) error {
+	app.find.regex = !app.find.regex
+	app.runFind()
+	return nil
+}
+
+// findSwitchField moves focus between the find and replace fields
+func (app *App) findSwitchField(g *gocui.Gui, v *gocui.View) error {
+	if v != nil && v.Name() == FIND_VIEW {
+		_, err := g.SetCurrentView(REPLACE_VIEW)
+		return err
+	}
+	_, err := g.SetCurrentView(FIND_VIEW)
+	return err
+}
+
+// =============================================================================
+// SEARCHING
+// =============================================================================
+
+// readFindFields copies the find and replace field contents into the find state
+func (app *App) readFindFields() {
+	if v, err := app.gui.View(FIND_VIEW); err == nil {
+		app.find.query = strings.Join(v.BufferLines(), "")
+	}
+	if v, err := app.gui.View(REPLACE_VIEW); err == nil {
+		app.find.replacement = strings.Join(v.BufferLines(), "")
+	}
+}
+
+// findTargetLines returns the text find works on: the edit buffer or the rendered note
+func (app *App) findTargetLines() []string {
+	if app.isEditMode {
+		if v, err := app.gui.View(MAIN_VIEW); err == nil {
+			return v.BufferLines()
+		}
+		return nil
+	}
+	return app.renderDocument(app.currentContent, 0).lines
+}
+
+// runFind recompiles the query, collects matches and selects the first one after the anchor
+func (app *App) runFind() {
+	f := &app.find
+	app.readFindFields()
+	f.message = ""
+	f.matches = nil
+	f.current = -1
+	app.cancelLargeSearch()
+
+	if err := f.compile(); err != nil {
+		f.message = "Invalid pattern"
+	}
+
+	if f.pattern != nil {
+		if app.isLargeFile && !app.isEditMode {
+			// The viewport is highlighted at once while the whole file is scanned
+			f.matches = findMatchesInLines(app.findTargetLines(), app.currentLine, f.pattern)
+			app.startLargeSearch()
+		} else {
+			f.matches = findMatchesInLines(app.findTargetLines(), 0, f.pattern)
+			for i, m := range f.matches {
+				if m.line > f.anchor.line || (m.line == f.anchor.line && m.start >= f.anchor.start) {
+					f.current = i
+					break
+				}
+			}
+			if f.current < 0 && len(f.matches) > 0 {
+				f.current = 0
+			}
+		}
+	}
+
+	app.repaintFindHighlights()
+	app.showCurrentMatch()
+	app.updateFindTitles()
+}
+
+// findStep moves to the next (dir > 0) or previous match
+func (app *App) findStep(dir int) error {
+	f := &app.find
+	if f.pattern == nil {
+		return nil
+	}
+
+	if app.isLargeFile && !app.isEditMode {
+		return app.findStepLargeFile(dir)
+	}
+
+	if len(f.matches) == 0 {
+		return nil
+	}
+	f.current = (f.current + dir + len(f.matches)) % len(f.matches)
+	f.anchor = f.matches[f.current]
+
+	app.repaintFindHighlights()
+	app.showCurrentMatch()
+	app.updateFindTitles()
+	return nil
+}
+
+// showCurrentMatch scrolls the main view so the current match is visible
+func (app *App) showCurrentMatch() {
+	f := &app.find
+	if f.current < 0 || f.current >= len(f.matches) || app.isLargeFile {
+		return
+	}
+	v, err := app.gui.View(MAIN_VIEW)
+	if err != nil {
+		return
+	}
+
+	m := f.matches[f.current]
+	v.SetCursor(m.start, m.line)
+	v.MoveCursor(0, 0) // Adjusts the origin so the match is on screen
+}
+
+// repaintFindHighlights redraws the main view with (or without) match highlighting
+func (app *App) repaintFindHighlights() {
+	if !app.isEditMode {
+		app.updateMainView()
+		return
+	}
+
+	v, err := app.gui.View(MAIN_VIEW)
+	if err != nil {
+		return
+	}
+	app.setEditLines(v, app.highlightFindMatches(v.BufferLines(), 0))
+}
+
+// highlightFindMatches wraps every match in color escapes; firstLine numbers lines[0]
+func (app *App) highlightFindMatches(lines []string, firstLine int) []string {
+	f := &app.find
+	if !f.active || f.pattern == nil {
+		return lines
+	}
+
+	current := findMatch{line: -1}
+	if f.current >= 0 && f.current < len(f.matches) {
+		current = f.matches[f.current]
+	}
+
+	result := make([]string, len(lines))
+	for i, line := range lines {
+		var b strings.Builder
+		last := 0
+		for _, loc := range f.pattern.FindAllStringIndex(line, -1) {
+			if loc[0] == loc[1] {
+				continue
+			}
+			style := FIND_MATCH_STYLE
+			if firstLine+i == current.line && utf8.RuneCountInString(line[:loc[0]]) == current.start {
+				style = FIND_CURRENT_STYLE
+			}
+			b.WriteString(line[last:loc[0]])
+			b.WriteString(style + line[loc[0]:loc[1]] + ANSI_RESET)
+			last = loc[1]
+		}
+		b.WriteString(line[last:])
+		result[i] = b.String()
+	}
+	return result
+}
+
+// =============================================================================
+// REPLACE
+// =============================================================================
+
+// replaceOne replaces the current match and moves to the next one
+func (app *App) replaceOne(g *gocui.Gui, v *gocui.View) error {
+	f := &app.find
+	if !app.isEditMode {
+		f.message = "Replace works in edit mode"
+		app.updateFindTitles()
+		return nil
+	}
+	app.readFindFields()
+	if f.current < 0 || f.current >= len(f.matches) {
+		return nil
+	}
+
+	mainView, err := g.View(MAIN_VIEW)
+	if err != nil {
+		return nil
+	}
+
+	m := f.matches[f.current]
+	lines := mainView.BufferLines()
+	line := lines[m.line]
+	for _, loc := range f.pattern.FindAllStringSubmatchIndex(line, -1) {
+		if utf8.RuneCountInString(line[:loc[0]]) != m.start {
+			continue
+		}
+		replacement := f.expand(line, loc)
+		lines[m.line] = line[:loc[0]] + replacement + line[loc[1]:]
+		f.anchor = findMatch{line: m.line, start: m.start + utf8.RuneCountInString(replacement)}
+		break
+	}
+
+	app.setEditLines(mainView, lines)
+	app.runFind()
+	return nil
+}
+
+// replaceAll replaces every non-empty match in the note
+func (app *App) replaceAll(g *gocui.Gui, v *gocui.View) error {
+	f := &app.find
+	if !app.isEditMode {
+		f.message = "Replace works in edit mode"
+		app.updateFindTitles()
+		return nil
+	}
+	app.readFindFields()
+	if f.pattern == nil {
+		return nil
+	}
+
+	mainView, err := g.View(MAIN_VIEW)
+	if err != nil {
+		return nil
+	}
+
+	// Replace the same non-empty matches find collected, grouped by line
+	lines := mainView.BufferLines()
+	starts := make(map[int][]int)
+	for _, m := range findMatchesInLines(lines, 0, f.pattern) {
+		starts[m.line] = append(starts[m.line], m.start)
+	}
+	count := 0
+	for i, cols := range starts {
+		var n int
+		lines[i], n = f.replaceSpans(lines[i], cols)
+		count += n
+	}
+
+	app.setEditLines(mainView, lines)
+	app.runFind()
+	f.message = fmt.Sprintf("Replaced %d", count)
+	app.updateFindTitles()
+	return nil
+}
+
+// replaceSpans replaces the non-empty matches in line that start at the rune
+// columns cols, last to first so earlier byte offsets stay valid, and returns
+// the new line and how many were replaced
+func (f *findState) replaceSpans(line string, cols []int) (string, int) {
+	want := make(map[int]bool, len(cols))
+	for _, col := range cols {
+		want[col] = true
+	}
+
+	locs := f.pattern.FindAllStringSubmatchIndex(line, -1)
+	result := line
+	count := 0
+	for i := len(locs) - 1; i >= 0; i-- {
+		loc := locs[i]
+		if loc[0] == loc[1] || !want[utf8.RuneCountInString(line[:loc[0]])] {
+			continue
+		}
+		result = result[:loc[0]] + f.expand(line, loc) + result[loc[1]:]
+		count++
+	}
+	return result, count
+}
+
+// expand returns the replacement for the match at loc in line, with $1 and
+// friends filled in when the query is a regex
+func (f *findState) expand(line string, loc []int) string {
+	if !f.regex {
+		return f.replacement
+	}
+	return string(f.pattern.ExpandString(nil, f.replacement, line, loc))
+}
+
+// setEditLines replaces the edit buffer while keeping the cursor and scroll position
+func (app *App) setEditLines(v *gocui.View, lines []string) {
+	cx, cy := v.Cursor()
+	ox, oy := v.Origin()
+	v.Clear()
+	fmt.Fprint(v, strings.Join(lines, "\n"))
+	v.SetOrigin(ox, oy)
+	v.SetCursor(cx, cy)
+	app.schedulePreview()
+}
@@ -0,0 +1,32 @@
+package main
+
+import "testing"
+
+func TestReplaceSpans(t *testing.T) {
+	tests := []struct {
+		query, replacement string
+		regex              bool
+		line, want         string
+		count              int
+	}{
+		{"cat", "dog", false, "cat, cat and cat", "dog, dog and dog", 3},
+		{"x*", "-", true, "axxb", "a-b", 1},                  // Zero-width matches stay
+		{"(\\w)é", "${1}e", true, "café thé", "cafe the", 2}, // Columns are runes
+		{"a+", "a", true, "€aaa €aa", "€a €a", 2},
+		{"$1", "x", false, "costs $1", "costs x", 1}, // Literal without a regex
+	}
+	for _, tt := range tests {
+		f := findState{query: tt.query, replacement: tt.replacement, regex: tt.regex, caseSensitive: true}
+		if err := f.compile(); err != nil {
+			t.Fatal(err)
+		}
+		var cols []int
+		for _, m := range findMatchesInLines([]string{tt.line}, 0, f.pattern) {
+			cols = append(cols, m.start)
+		}
+		got, n := f.replaceSpans(tt.line, cols)
+		if got != tt.want || n != tt.count {
+			t.Errorf("replace %q in %q: got %q (%d), want %q (%d)", tt.query, tt.line, got, n, tt.want, tt.count)
+		}
+	}
+}
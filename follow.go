@@ -0,0 +1,183 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// =============================================================================
+// FOLLOW MODE
+// =============================================================================
+
+// followState tracks a file shown in the large-file viewer as it grows
+type followState struct {
+	path      string
+	info      os.FileInfo   // the file as of the last check
+	auto      bool          // keep the viewer at the bottom
+	shownLine int           // first line the viewer was last scrolled to
+	stop      chan struct{} // stops the polling goroutine
+}
+
+// toggleFollow starts or stops following the current file like `tail -f`.
+// Followed files are shown in the large-file viewer whatever their size.
+func (app *App) toggleFollow(g *gocui.Gui, v *gocui.View) error {
+	if app.follow != nil {
+		app.stopFollow()
+		if app.fileSize <= LARGE_FILE_THRESHOLD {
+			app.loadCurrentItem() // Back to the rendered note
+		}
+		app.updateMainView()
+		app.updateStatusBar()
+		return nil
+	}
+
+	if app.isEditMode || len(app.items) == 0 || app.items[app.currentItem].IsFolder || app.binary != nil {
+		return nil
+	}
+	path := filepath.Join(app.notesDir, app.items[app.currentItem].Path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+
+	state := &followState{path: path, info: info, auto: true, stop: make(chan struct{})}
+	app.follow = state
+	app.loadCurrentItem()
+	if err := app.goToBottom(); err != nil {
+		return err
+	}
+	state.shownLine = app.currentLine
+
+	// Poll the file; the checks themselves run on the UI goroutine
+	go func() {
+		ticker := time.NewTicker(FOLLOW_POLL_MS * time.Millisecond)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-state.stop:
+				return
+			case <-ticker.C:
+				app.events.post(followTickMsg{state})
+			}
+		}
+	}()
+	return nil
+}
+
+// stopFollow stops following the current file
+func (app *App) stopFollow() {
+	if app.follow != nil {
+		close(app.follow.stop)
+		app.follow = nil
+	}
+}
+
+// followCheck picks up lines added to the followed file, or reloads it when
+// it has been truncated or replaced, and keeps the viewer at the bottom until
+// the user scrolls up
+func (app *App) followCheck() error {
+	s := app.follow
+	if app.indexJob != nil || app.isEditMode || !app.isLargeFile {
+		return nil // Wait for the count to finish
+	}
+
+	info, err := os.Stat(s.path)
+	if err != nil {
+		return nil // Rotated away; the new file may not exist yet
+	}
+
+	// Scrolling up pauses the autoscroll, getting back to the bottom resumes it
+	bottom := app.totalLines - app.viewportHeight
+	wasAuto := s.auto
+	if app.currentLine < s.shownLine {
+		s.auto = false
+	}
+	if app.currentLine >= bottom {
+		s.auto = true
+	}
+
+	switch {
+	case !os.SameFile(s.info, info) || info.Size() < s.info.Size() ||
+		(info.Size() == s.info.Size() && !info.ModTime().Equal(s.info.ModTime())):
+		// Truncated, rewritten or rotated: start over
+		if err := app.followReload(info); err != nil {
+			return nil
+		}
+		s.auto = true
+	case info.Size() > s.info.Size() && app.lineIndex != nil:
+		oldTotal := app.totalLines
+		if err := app.lineIndex.extend(app.fileHandle, info, nil, nil); err != nil {
+			return nil
+		}
+		app.fileSize = info.Size()
+		app.totalLines = app.lineIndex.Lines
+		app.appendToCache(oldTotal)
+	default:
+		if s.auto && app.currentLine != s.shownLine {
+			s.shownLine = app.currentLine
+		}
+		if s.auto != wasAuto {
+			app.updateStatusBar()
+		}
+		return nil
+	}
+	s.info = info
+
+	if s.auto {
+		if err := app.goToBottom(); err != nil {
+			return err
+		}
+	} else if err := app.refreshViewport(); err != nil {
+		return err
+	}
+	s.shownLine = app.currentLine
+	return nil
+}
+
+// followReload reopens the followed file from the start
+func (app *App) followReload(info os.FileInfo) error {
+	path := app.follow.path
+	if err := app.openFileForReading(path); err != nil {
+		return err
+	}
+	app.cancelLargeSearch()
+	app.fileSize = info.Size()
+	app.lineCache = nil
+	app.cacheStartLine = -1
+	app.cacheEndLine = -1
+	app.currentLine = 0
+	return app.startLineIndexing(path) // Back at the bottom once indexed
+}
+
+// appendToCache reads the lines added at the end of the file into the cache,
+// when the cache reached the old end
+func (app *App) appendToCache(oldTotal int) {
+	if app.lineCache == nil || app.cacheEndLine < oldTotal || app.cacheColumn != app.segmentColumn() {
+		return
+	}
+
+	// The old last line is read again, it may not have been finished
+	from := oldTotal - 1
+	if from < app.cacheStartLine {
+		from = app.cacheStartLine
+	}
+	lines, cut, err := app.readLineSegments(from, app.totalLines)
+	if err != nil {
+		app.lineCache = nil
+		return
+	}
+	keep := from - app.cacheStartLine
+	app.lineCache = append(app.lineCache[:keep], lines...)
+	app.lineCut = append(app.lineCut[:keep], cut...)
+	app.cacheEndLine = from + len(lines)
+
+	// Keep the cache from growing without bound while following
+	if extra := len(app.lineCache) - 2*CACHE_LINES; extra > 0 {
+		app.lineCache = app.lineCache[extra:]
+		app.lineCut = app.lineCut[extra:]
+		app.cacheStartLine += extra
+	}
+}
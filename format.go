@@ -0,0 +1,253 @@
+package main
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// =============================================================================
+// MARKDOWN FORMATTING
+// =============================================================================
+
+// formatRange returns the runes to format: the Vim visual selection if there is one,
+// otherwise the word under the cursor (possibly empty)
+func (app *App) formatRange(lines [][]rune, flat []rune, cursor vimPos) (int, int) {
+	if app.vimEnabled && (app.vim.mode == vimVisual || app.vim.mode == vimVisualLine) {
+		from, to := app.vim.anchor, cursor
+		if vimOffset(lines, to) < vimOffset(lines, from) {
+			from, to = to, from
+		}
+		if app.vim.mode == vimVisualLine {
+			from.x = 0
+			to.x = len(lines[to.y])
+		} else if to.x < len(lines[to.y]) {
+			to.x++ // Visual selections include the character under the cursor
+		}
+		return vimOffset(lines, from), vimOffset(lines, to)
+	}
+
+	isWord := func(r rune) bool {
+		return unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' || r == '\''
+	}
+	offset := vimOffset(lines, cursor)
+	start, end := offset, offset
+	for start > 0 && isWord(flat[start-1]) {
+		start--
+	}
+	for end < len(flat) && isWord(flat[end]) {
+		end++
+	}
+	return start, end
+}
+
+// markerAt reports whether marker sits exactly at flat[i:], not as part of a longer run
+func markerAt(flat []rune, i int, marker []rune) bool {
+	if i < 0 || i+len(marker) > len(flat) {
+		return false
+	}
+	for k, r := range marker {
+		if flat[i+k] != r {
+			return false
+		}
+	}
+	if i > 0 && flat[i-1] == marker[0] {
+		return false
+	}
+	end := i + len(marker)
+	return end >= len(flat) || flat[end] != marker[len(marker)-1]
+}
+
+// toggleInlineFormat wraps the selection or word in marker, or unwraps it if already wrapped
+func (app *App) toggleInlineFormat(v *gocui.View, marker string) error {
+	if !app.isEditMode || !v.Editable {
+		return nil
+	}
+
+	lines := vimBufferLines(v)
+	flat := vimFlatten(lines)
+	cx, cy := v.Cursor()
+	cursorPos := vimClamp(lines, vimPos{cx, cy}, false)
+	cursor := vimOffset(lines, cursorPos)
+	start, end := app.formatRange(lines, flat, cursorPos)
+
+	m := []rune(marker)
+	n := len(m)
+	var out []rune
+	switch {
+	case markerAt(flat, start-n, m) && markerAt(flat, end, m):
+		// Markers just outside the range: remove them
+		out = append(out, flat[:start-n]...)
+		out = append(out, flat[start:end]...)
+		out = append(out, flat[end+n:]...)
+		cursor -= n
+	case end-start >= 2*n && markerAt(flat, start, m) && markerAt(flat, end-n, m):
+		// The range itself is wrapped: remove the markers inside it
+		out = append(out, flat[:start]...)
+		out = append(out, flat[start+n:end-n]...)
+		out = append(out, flat[end:]...)
+		if cursor > start {
+			cursor -= n
+		}
+	default:
+		out = append(out, flat[:start]...)
+		out = append(out, m...)
+		out = append(out, flat[start:end]...)
+		out = append(out, m...)
+		out = append(out, flat[end:]...)
+		cursor += n
+	}
+
+	if cursor < 0 {
+		cursor = 0
+	}
+	app.setFormattedBuffer(v, out, cursor)
+	return nil
+}
+
+// setFormattedBuffer replaces the edit buffer and leaves any Vim visual mode
+func (app *App) setFormattedBuffer(v *gocui.View, flat []rune, cursor int) {
+	if app.vimEnabled && app.vim.mode != vimInsert {
+		app.resetVim()
+	}
+	lines := vimSplit(flat)
+	pos := vimPosAt(lines, cursor)
+	app.setEditLinesAt(v, strings.Split(string(flat), "\n"), pos.x, pos.y)
+	app.updateStatusBar()
+}
+
+// formatBold toggles **bold**
+func (app *App) formatBold(g *gocui.Gui, v *gocui.View) error {
+	return app.toggleInlineFormat(v, "**")
+}
+
+// formatItalic toggles *italic*
+func (app *App) formatItalic(g *gocui.Gui, v *gocui.View) error {
+	return app.toggleInlineFormat(v, "*")
+}
+
+// formatCode toggles `inline code`
+func (app *App) formatCode(g *gocui.Gui, v *gocui.View) error {
+	return app.toggleInlineFormat(v, "`")
+}
+
+// formatStrikethrough toggles ~~strikethrough~~
+func (app *App) formatStrikethrough(g *gocui.Gui, v *gocui.View) error {
+	return app.toggleInlineFormat(v, "~~")
+}
+
+// formatHighlight toggles ==highlight==
+func (app *App) formatHighlight(g *gocui.Gui, v *gocui.View) error {
+	return app.toggleInlineFormat(v, "==")
+}
+
+// cycleHeading cycles the current line through heading levels 1-6 and back to text
+func (app *App) cycleHeading(g *gocui.Gui, v *gocui.View) error {
+	if !app.isEditMode || !v.Editable {
+		return nil
+	}
+
+	lines, x, y := editBufferLines(v)
+	line := lines[y]
+
+	level := 0
+	for level < len(line) && line[level] == '#' {
+		level++
+	}
+	text := line
+	if level > 0 && (level == len(line) || line[level] == ' ') {
+		text = strings.TrimPrefix(line[level:], " ")
+	} else {
+		level = 0
+	}
+
+	prefix := ""
+	if level < MAX_HEADING_LEVEL {
+		prefix = strings.Repeat("#", level+1) + " "
+	}
+	lines[y] = prefix + text
+
+	x += len([]rune(lines[y])) - len([]rune(line))
+	if x < len([]rune(prefix)) {
+		x = len([]rune(prefix))
+	}
+	app.setEditLinesAt(v, lines, x, y)
+	return nil
+}
+
+// =============================================================================
+// LINKS
+// =============================================================================
+
+// insertLink opens a picker of vault notes and inserts a link to the chosen one.
+// Typing a URL that matches no note inserts a link to the URL instead.
+func (app *App) insertLink(g *gocui.Gui, v *gocui.View) error {
+	if !app.isEditMode || !v.Editable {
+		return nil
+	}
+
+	// Remember what to replace: the Vim visual selection, or nothing
+	lines := vimBufferLines(v)
+	flat := vimFlatten(lines)
+	cx, cy := v.Cursor()
+	cursorPos := vimClamp(lines, vimPos{cx, cy}, false)
+	start := vimOffset(lines, cursorPos)
+	end := start
+	linkText := ""
+	if app.vimEnabled && (app.vim.mode == vimVisual || app.vim.mode == vimVisualLine) {
+		start, end = app.formatRange(lines, flat, cursorPos)
+		linkText = string(flat[start:end])
+	}
+
+	var items []pickerItem
+	titles := make(map[string]string)
+	for _, note := range app.vault.notes {
+		items = append(items, pickerItem{label: note.Title + "  (" + note.Path + ")", value: note.Path})
+		titles[note.Path] = note.Title
+	}
+
+	title := " Insert Link - type to filter notes, or a URL "
+	return app.openPicker(title, items, func(item *pickerItem, query string) error {
+		var link string
+		switch {
+		case item != nil:
+			text := linkText
+			if text == "" {
+				text = titles[item.value]
+			}
+			link = "[" + text + "](" + app.linkTarget(item.value) + ")"
+		case query != "":
+			text := linkText
+			if text == "" {
+				text = query
+			}
+			link = "[" + text + "](" + query + ")"
+		default:
+			return nil
+		}
+
+		mainView, err := g.View(MAIN_VIEW)
+		if err != nil {
+			return nil
+		}
+		out := append([]rune{}, flat[:start]...)
+		out = append(out, []rune(link)...)
+		out = append(out, flat[end:]...)
+		app.setFormattedBuffer(mainView, out, start+len([]rune(link)))
+		return nil
+	})
+}
+
+// linkTarget returns the markdown link target for a vault note, relative to the note being edited
+func (app *App) linkTarget(notePath string) string {
+	target := notePath
+	if app.editPath != "" {
+		if rel, err := filepath.Rel(filepath.Dir(app.editPath), notePath); err == nil {
+			target = rel
+		}
+	}
+	return (&url.URL{Path: filepath.ToSlash(target)}).EscapedPath()
+}
@@ -3,14 +3,17 @@ module cui-notes
 go 1.25.1
 
 require (
-	github.com/atotto/clipboard v0.1.4 // indirect
-	github.com/awesome-gocui/gocui v1.1.0 // indirect
+	github.com/atotto/clipboard v0.1.4
+	github.com/awesome-gocui/gocui v1.1.0
+	github.com/mattn/go-runewidth v0.0.10
+	golang.org/x/sys v0.0.0-20201119102817-f84b799fce68
+)
+
+require (
 	github.com/gdamore/encoding v1.0.0 // indirect
 	github.com/gdamore/tcell/v2 v2.4.0 // indirect
 	github.com/lucasb-eyer/go-colorful v1.0.3 // indirect
-	github.com/mattn/go-runewidth v0.0.10 // indirect
 	github.com/rivo/uniseg v0.1.0 // indirect
-	golang.org/x/sys v0.0.0-20201119102817-f84b799fce68 // indirect
 	golang.org/x/term v0.0.0-20201210144234-2321bbc49cbf // indirect
 	golang.org/x/text v0.3.3 // indirect
 )
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// =============================================================================
+// LINE NUMBERS
+// =============================================================================
+
+// gutterWidth returns the columns taken by the line-number gutter, 0 when hidden
+func (app *App) gutterWidth() int {
+	if !app.lineNumbers || app.binary != nil {
+		return 0
+	}
+
+	last := strings.Count(app.currentContent, "\n") + 1
+	if app.largeEdit != nil {
+		last = app.largeEdit.table.total + LARGE_EDIT_WINDOW // Room to grow while editing
+	} else if app.isLargeFile {
+		last = app.totalLines
+	} else if v, err := app.gui.View(MAIN_VIEW); err == nil && app.isEditMode {
+		last = len(v.BufferLines())
+	}
+
+	digits := len(strconv.Itoa(last))
+	if digits < GUTTER_MIN_DIGITS {
+		digits = GUTTER_MIN_DIGITS
+	}
+	return digits + 1 // One column between the numbers and the text
+}
+
+// layoutGutter places the gutter at the left of the main area and returns
+// where the main view starts
+func (app *App) layoutGutter(g *gocui.Gui, x0, y0, y1 int) (int, error) {
+	width := app.gutterWidth()
+	if width == 0 {
+		g.DeleteView(GUTTER_VIEW)
+		return x0, nil
+	}
+
+	// Frameless, so the numbers line up with the main view's text rows
+	if v, err := g.SetView(GUTTER_VIEW, x0, y0, x0+width+1, y1, 0); err != nil {
+		if err != gocui.ErrUnknownView {
+			return x0, err
+		}
+		v.Frame = false
+		v.FgColor = gocui.ColorYellow
+	}
+	return x0 + width + 1, nil
+}
+
+// gutterFirstLine returns the source line number of the main view's first buffer line
+func (app *App) gutterFirstLine() int {
+	if app.largeEdit != nil {
+		return app.largeEdit.winStart
+	}
+	if app.isLargeFile && !app.isEditMode {
+		return app.currentLine
+	}
+	return 0
+}
+
+// updateGutter numbers the main view rows that are on screen. Rendering is line
+// for line, so in view mode these are the source line numbers too.
+func (app *App) updateGutter() {
+	gutter, err := app.gui.View(GUTTER_VIEW)
+	if err != nil {
+		return
+	}
+	mainView, err := app.gui.View(MAIN_VIEW)
+	if err != nil {
+		return
+	}
+
+	// Widen the gutter once the numbers outgrow it
+	gutterWidth, _ := gutter.Size()
+	if width := app.gutterWidth(); width != gutterWidth {
+		app.relayoutMainArea(app.gui)
+		gutterWidth, _ = gutter.Size()
+	}
+
+	width, height := mainView.Size()
+	_, oy := mainView.Origin()
+	first := app.gutterFirstLine()
+
+	var b strings.Builder
+	row := 0
+	for i, line := range mainView.BufferLines() {
+		if row >= oy+height {
+			break
+		}
+		rows := len(wrapRows([]rune(line), width))
+		for r := 0; r < rows; r++ {
+			if row >= oy && row < oy+height {
+				if r == 0 {
+					fmt.Fprintf(&b, "%*d", gutterWidth-1, first+i+1)
+				}
+				b.WriteString("\n")
+			}
+			row++
+		}
+	}
+
+	gutter.Clear()
+	fmt.Fprint(gutter, b.String())
+}
+
+// toggleLineNumbers shows or hides the line-number gutter
+func (app *App) toggleLineNumbers(g *gocui.Gui, v *gocui.View) error {
+	app.lineNumbers = !app.lineNumbers
+	if err := app.relayoutMainArea(g); err != nil {
+		return err
+	}
+	app.updateGutter()
+	return nil
+}
+
+// =============================================================================
+// GO TO LINE
+// =============================================================================
+
+// goToLine asks for a line number and moves there
+func (app *App) goToLine(g *gocui.Gui, v *gocui.View) error {
+	if len(app.items) == 0 || app.items[app.currentItem].IsFolder || app.binary != nil {
+		return nil
+	}
+
+	total := len(v.BufferLines())
+	if app.largeEdit != nil {
+		total = app.largeEditLines(v)
+	} else if app.isLargeFile {
+		total = app.totalLines
+	}
+
+	prompt := fmt.Sprintf("Line number (1-%d):", total)
+	app.showDialog("goto_line", " Go to Line ", prompt, func(input string) error {
+		mainView, err := g.SetCurrentView(MAIN_VIEW)
+		if err != nil {
+			return nil
+		}
+		n, err := strconv.Atoi(strings.TrimSpace(input))
+		if err != nil {
+			return nil
+		}
+		if n > total {
+			n = total
+		}
+		if n < 1 {
+			n = 1
+		}
+		return app.jumpToLine(mainView, n-1)
+	})
+	return nil
+}
+
+// jumpToLine shows source line y (0-based) near the top of the main view
+func (app *App) jumpToLine(v *gocui.View, y int) error {
+	_, height := v.Size()
+	screenRow := height / 3
+
+	switch {
+	case app.largeEdit != nil:
+		if err := app.moveEditWindow(v, y, screenRow); err != nil {
+			return err
+		}
+		placeCursorLine(v, screenRow)
+
+	case app.isEditMode:
+		v.SetCursor(0, y)
+		placeCursorLine(v, screenRow)
+
+	case app.isLargeFile:
+		// Page the line in directly rather than scrolling to it
+		maxLine := app.totalLines - app.viewportHeight
+		if maxLine < 0 {
+			maxLine = 0
+		}
+		if y > maxLine {
+			y = maxLine
+		}
+		app.currentLine = y
+		if err := app.ensureLinesInCache(app.currentLine, app.currentLine+app.viewportHeight); err != nil {
+			return err
+		}
+		content, err := app.getViewportContent()
+		if err != nil {
+			return err
+		}
+		app.currentContent = content
+		app.updateMainView()
+
+	default:
+		width, _ := v.Size()
+		lines := v.BufferLines()
+		if y >= len(lines) {
+			y = len(lines) - 1
+		}
+		if y < 0 {
+			return nil
+		}
+		v.SetOrigin(0, lineRow(lines, y, width))
+	}
+
+	app.updateStatusBar()
+	return nil
+}
@@ -14,98 +14,17 @@ import (
 // KEYBINDINGS
 // =============================================================================
 
-// setKeybindings configures all keyboard shortcuts
+// setKeybindings configures all keyboard shortcuts from the active keymap
 func (app *App) setKeybindings() error {
-	// Global keybindings
-	if err := app.gui.SetKeybinding("", gocui.KeyCtrlC, gocui.ModNone, app.quit); err != nil {
-		return err
-	}
-	if err := app.gui.SetKeybinding("", gocui.KeyCtrlN, gocui.ModNone, app.newNote); err != nil {
-		return err
-	}
-	if err := app.gui.SetKeybinding("", gocui.KeyCtrlF, gocui.ModNone, app.newFolder); err != nil {
-		return err
-	}
-	if err := app.gui.SetKeybinding("", gocui.KeyF5, gocui.ModNone, app.refreshItems); err != nil {
-		return err
-	}
-	if err := app.gui.SetKeybinding("", gocui.KeyCtrlR, gocui.ModNone, app.refreshItems); err != nil {
-		return err
-	}
-	// Global keybinding for sidebar toggle (Tab)
-	if err := app.gui.SetKeybinding("", gocui.KeyTab, gocui.ModNone, app.toggleSidebar); err != nil {
+	if err := app.bindKeymap(); err != nil {
 		return err
 	}
 
-	// Sidebar keybindings
-	if err := app.gui.SetKeybinding(SIDEBAR_VIEW, gocui.KeyArrowUp, gocui.ModNone, app.cursorUp); err != nil {
-		return err
-	}
-	if err := app.gui.SetKeybinding(SIDEBAR_VIEW, gocui.KeyArrowDown, gocui.ModNone, app.cursorDown); err != nil {
-		return err
-	}
-	if err := app.gui.SetKeybinding(SIDEBAR_VIEW, gocui.KeyEnter, gocui.ModNone, app.selectItem); err != nil {
-		return err
-	}
-	if err := app.gui.SetKeybinding(SIDEBAR_VIEW, 'd', gocui.ModNone, app.confirmDeleteItem); err != nil {
-		return err
-	}
-	if err := app.gui.SetKeybinding(SIDEBAR_VIEW, 'r', gocui.ModNone, app.renameItem); err != nil {
-		return err
-	}
+	// Mouse bindings are not part of the keymap
 	if err := app.gui.SetKeybinding(SIDEBAR_VIEW, gocui.MouseLeft, gocui.ModNone, app.handleSidebarClick); err != nil {
 		return err
 	}
 
-	// Main view keybindings
-	if err := app.gui.SetKeybinding(MAIN_VIEW, gocui.KeyEnter, gocui.ModNone, app.handleEnterInMainView); err != nil {
-		return err
-	}
-	if err := app.gui.SetKeybinding(MAIN_VIEW, gocui.KeyEsc, gocui.ModNone, app.exitEditMode); err != nil {
-		return err
-	}
-	if err := app.gui.SetKeybinding(MAIN_VIEW, gocui.KeyCtrlS, gocui.ModNone, app.saveNote); err != nil {
-		return err
-	}
-	if err := app.gui.SetKeybinding(MAIN_VIEW, gocui.KeyCtrlC, gocui.ModNone, app.copySelection); err != nil {
-		return err
-	}
-	if err := app.gui.SetKeybinding(MAIN_VIEW, gocui.KeyCtrlV, gocui.ModNone, app.pasteClipboard); err != nil {
-		return err
-	}
-
-	// Arrow keys for scrolling in view mode (cursor movement in edit mode is handled by gocui)
-	if err := app.gui.SetKeybinding(MAIN_VIEW, gocui.KeyArrowUp, gocui.ModNone, app.handleScrollUp); err != nil {
-		return err
-	}
-	if err := app.gui.SetKeybinding(MAIN_VIEW, gocui.KeyArrowDown, gocui.ModNone, app.handleScrollDown); err != nil {
-		return err
-	}
-
-	// Page up/down for larger jumps
-	if err := app.gui.SetKeybinding(MAIN_VIEW, gocui.KeyPgup, gocui.ModNone, app.handlePageUp); err != nil {
-		return err
-	}
-	if err := app.gui.SetKeybinding(MAIN_VIEW, gocui.KeyPgdn, gocui.ModNone, app.handlePageDown); err != nil {
-		return err
-	}
-
-	// Home/End keys for top/bottom
-	if err := app.gui.SetKeybinding(MAIN_VIEW, gocui.KeyHome, gocui.ModNone, app.handleGoToTop); err != nil {
-		return err
-	}
-	if err := app.gui.SetKeybinding(MAIN_VIEW, gocui.KeyEnd, gocui.ModNone, app.handleGoToBottom); err != nil {
-		return err
-	}
-
-	// Input dialog keybindings
-	if err := app.gui.SetKeybinding(INPUT_VIEW, gocui.KeyEnter, gocui.ModNone, app.handleDialogConfirm); err != nil {
-		return err
-	}
-	if err := app.gui.SetKeybinding(INPUT_VIEW, gocui.KeyEsc, gocui.ModNone, app.handleDialogCancel); err != nil {
-		return err
-	}
-
 	return nil
 }
 
@@ -113,8 +32,16 @@ func (app *App) setKeybindings() error {
 // CLIPBOARD OPERATIONS
 // =============================================================================
 
-// copyToClipboard copies text to the system clipboard
+// copyToClipboard copies text to the clipboard backend and the built-in register
 func (app *App) copyToClipboard(text string) error {
+	app.clipboard.remember(text)
+	switch app.clipboard.mode {
+	case clipboardInternal:
+		return nil
+	case clipboardOSC52:
+		return writeOSC52(text)
+	}
+
 	// Try to use the clipboard library first
 	err := clipboard.WriteAll(text)
 	if err == nil {
@@ -140,11 +67,20 @@ func (app *App) copyToClipboard(text string) error {
 		return nil
 	}
 
+	// No system clipboard (e.g. over SSH): let the terminal take it
+	if app.clipboard.mode == clipboardAuto {
+		return writeOSC52(text)
+	}
+
 	return fmt.Errorf("failed to copy to clipboard: %v", err)
 }
 
-// pasteFromClipboard gets text from the system clipboard
+// pasteFromClipboard gets text from the system clipboard, or the built-in register
 func (app *App) pasteFromClipboard() (string, error) {
+	if app.clipboard.mode == clipboardInternal || app.clipboard.mode == clipboardOSC52 {
+		return app.clipboard.register, nil // OSC 52 can't be read back reliably
+	}
+
 	// Try to use the clipboard library first
 	text, err := clipboard.ReadAll()
 	if err == nil {
@@ -166,6 +102,11 @@ func (app *App) pasteFromClipboard() (string, error) {
 		return strings.TrimSpace(string(output)), nil
 	}
 
+	// No system clipboard: paste the last copy made in the app
+	if app.clipboard.register != "" {
+		return app.clipboard.register, nil
+	}
+
 	return "", fmt.Errorf("failed to paste from clipboard: %v", err)
 }
 
@@ -193,6 +134,8 @@ func (app *App) hasScreenSizeChanged() bool {
 
 // quit exits the application
 func (app *App) quit(g *gocui.Gui, v *gocui.View) error {
+	// Keep unsaved edits so they can be recovered on the next start
+	app.flushSwap()
 	return gocui.ErrQuit
 }
 
@@ -209,23 +152,13 @@ func (app *App) switchPanel(g *gocui.Gui, v *gocui.View) error {
 
 // cursorUp moves the cursor up in the sidebar
 func (app *App) cursorUp(g *gocui.Gui, v *gocui.View) error {
-	if app.currentItem > 0 {
-		app.currentItem--
-		app.loadCurrentItem()
-		app.updateSidebar()
-		app.updateHeader()
-	}
+	app.selectSidebarItem(app.currentItem - 1)
 	return nil
 }
 
 // cursorDown moves the cursor down in the sidebar
 func (app *App) cursorDown(g *gocui.Gui, v *gocui.View) error {
-	if app.currentItem < len(app.items)-1 {
-		app.currentItem++
-		app.loadCurrentItem()
-		app.updateSidebar()
-		app.updateHeader()
-	}
+	app.selectSidebarItem(app.currentItem + 1)
 	return nil
 }
 
@@ -237,8 +170,9 @@ func (app *App) refreshItems(g *gocui.Gui, v *gocui.View) error {
 		currentItemPath = app.items[app.currentItem].Path
 	}
 
-	// Reload items
+	// Reload items, and the titles and tags offered while typing
 	app.loadItems()
+	app.refreshVaultLists()
 
 	// Try to restore selection
 	for i, item := range app.items {
@@ -302,23 +236,24 @@ func (app *App) toggleSidebar(g *gocui.Gui, v *gocui.View) error {
 
 // handleScrollUp handles scroll up events (arrow keys and page up)
 func (app *App) handleScrollUp(g *gocui.Gui, v *gocui.View) error {
-	if v.Editable {
-		// In edit mode, handle cursor movement with viewport following
-		cx, cy := v.Cursor()
-		ox, oy := v.Origin()
+	if app.completionShown() {
+		return app.moveCompletion(-1)
+	}
 
-		// Simple approach: try to move cursor up, if at edge try to scroll
-		if cy > 0 {
-			// Move cursor up within view
-			v.SetCursor(cx, cy-1)
-		} else if oy > 0 {
-			// Cursor is at top of view, scroll the view up
-			v.SetOrigin(ox, oy-1)
+	if v.Editable {
+		// In edit mode, move the cursor up one wrapped row; large files
+		// page in the previous window at the top of the loaded one
+		if !app.moveCursorVisual(v, -1) {
+			app.largeEditEdge(v, -1)
 		}
+		app.vimPaintSelection(v)
 		return nil
 	}
 
 	// View mode scrolling
+	if app.binary != nil && app.binary.hexdump {
+		return app.scrollHexdump(-1)
+	}
 	if app.isLargeFile {
 		return app.scrollUp()
 	} else {
@@ -333,37 +268,24 @@ func (app *App) handleScrollUp(g *gocui.Gui, v *gocui.View) error {
 
 // handleScrollDown handles scroll down events
 func (app *App) handleScrollDown(g *gocui.Gui, v *gocui.View) error {
-	if v.Editable {
-		// In edit mode, handle cursor movement with viewport following
-		cx, cy := v.Cursor()
-		ox, oy := v.Origin()
-		_, maxY := v.Size()
-
-		// Get total lines in buffer to check bounds
-		v.Rewind()
-		buffer := v.ViewBuffer()
-		lines := strings.Split(buffer, "\n")
-		totalLines := len(lines)
-
-		// Simple approach: try to move cursor down, if at edge try to scroll
-		currentLine := cy + oy     // Current absolute line position (0-based)
-		lastLine := totalLines - 1 // Last line index (0-based)
+	if app.completionShown() {
+		return app.moveCompletion(1)
+	}
 
-		if cy < maxY-1 {
-			// Cursor can move down within view - check if there's a line below
-			if currentLine < lastLine {
-				v.SetCursor(cx, cy+1)
-			}
-		} else {
-			// Cursor is at bottom of view - try to scroll down
-			if currentLine < lastLine {
-				v.SetOrigin(ox, oy+1)
-			}
+	if v.Editable {
+		// In edit mode, move the cursor down one wrapped row; large files
+		// page in the next window at the bottom of the loaded one
+		if !app.moveCursorVisual(v, 1) {
+			app.largeEditEdge(v, 1)
 		}
+		app.vimPaintSelection(v)
 		return nil
 	}
 
 	// View mode scrolling
+	if app.binary != nil && app.binary.hexdump {
+		return app.scrollHexdump(1)
+	}
 	if app.isLargeFile {
 		return app.scrollDown()
 	} else {
@@ -381,12 +303,80 @@ func (app *App) handleScrollDown(g *gocui.Gui, v *gocui.View) error {
 	}
 }
 
+// handleCursorLeft moves the cursor left in edit mode
+func (app *App) handleCursorLeft(g *gocui.Gui, v *gocui.View) error {
+	if v.Editable {
+		v.MoveCursor(-1, 0)
+	}
+	return nil
+}
+
+// handleCursorRight moves the cursor right in edit mode
+func (app *App) handleCursorRight(g *gocui.Gui, v *gocui.View) error {
+	if v.Editable {
+		v.MoveCursor(1, 0)
+	}
+	return nil
+}
+
+// handleScrollLeft scrolls a large file left in view mode; in edit mode the
+// key goes to the editor as usual
+func (app *App) handleScrollLeft(g *gocui.Gui, v *gocui.View) error {
+	if v.Editable {
+		if v.Editor != nil {
+			v.Editor.Edit(v, gocui.KeyArrowLeft, 0, gocui.ModNone)
+		}
+		return nil
+	}
+	if app.isLargeFile {
+		return app.scrollHorizontal(v, -1)
+	}
+	return nil
+}
+
+// handleScrollRight scrolls a large file right in view mode; in edit mode the
+// key goes to the editor as usual
+func (app *App) handleScrollRight(g *gocui.Gui, v *gocui.View) error {
+	if v.Editable {
+		if v.Editor != nil {
+			v.Editor.Edit(v, gocui.KeyArrowRight, 0, gocui.ModNone)
+		}
+		return nil
+	}
+	if app.isLargeFile {
+		return app.scrollHorizontal(v, 1)
+	}
+	return nil
+}
+
+// handleLineStart moves the cursor to the start of the line in edit mode
+func (app *App) handleLineStart(g *gocui.Gui, v *gocui.View) error {
+	if v.Editable {
+		v.EditGotoToStartOfLine()
+	}
+	return nil
+}
+
+// handleLineEnd moves the cursor to the end of the line in edit mode
+func (app *App) handleLineEnd(g *gocui.Gui, v *gocui.View) error {
+	if v.Editable {
+		v.EditGotoToEndOfLine()
+	}
+	return nil
+}
+
 // handlePageUp handles page up events
 func (app *App) handlePageUp(g *gocui.Gui, v *gocui.View) error {
 	if v.Editable {
+		if app.largeEdit != nil {
+			return app.largeEditPage(v, -1)
+		}
 		return nil // Don't scroll in edit mode - let default cursor movement happen
 	}
 
+	if app.binary != nil && app.binary.hexdump {
+		return app.scrollHexdump(-app.hexdumpRows())
+	}
 	if app.isLargeFile {
 		return app.pageUp()
 	} else {
@@ -405,9 +395,15 @@ func (app *App) handlePageUp(g *gocui.Gui, v *gocui.View) error {
 // handlePageDown handles page down events
 func (app *App) handlePageDown(g *gocui.Gui, v *gocui.View) error {
 	if v.Editable {
+		if app.largeEdit != nil {
+			return app.largeEditPage(v, 1)
+		}
 		return nil // Don't scroll in edit mode - let default cursor movement happen
 	}
 
+	if app.binary != nil && app.binary.hexdump {
+		return app.scrollHexdump(app.hexdumpRows())
+	}
 	if app.isLargeFile {
 		return app.pageDown()
 	} else {
@@ -433,9 +429,13 @@ func (app *App) handlePageDown(g *gocui.Gui, v *gocui.View) error {
 // handleGoToTop handles go to top events
 func (app *App) handleGoToTop(g *gocui.Gui, v *gocui.View) error {
 	if v.Editable {
-		return nil // Don't scroll in edit mode - let default cursor movement happen
+		app.moveToRowEdge(v, false) // Start of the wrapped row in edit mode
+		return nil
 	}
 
+	if app.binary != nil && app.binary.hexdump {
+		return app.scrollHexdump(-int(app.binary.size))
+	}
 	if app.isLargeFile {
 		return app.goToTop()
 	} else {
@@ -449,9 +449,13 @@ func (app *App) handleGoToTop(g *gocui.Gui, v *gocui.View) error {
 // handleGoToBottom handles go to bottom events
 func (app *App) handleGoToBottom(g *gocui.Gui, v *gocui.View) error {
 	if v.Editable {
-		return nil // Don't scroll in edit mode - let default cursor movement happen
+		app.moveToRowEdge(v, true) // End of the wrapped row in edit mode
+		return nil
 	}
 
+	if app.binary != nil && app.binary.hexdump {
+		return app.scrollHexdump(int(app.binary.size))
+	}
 	if app.isLargeFile {
 		return app.goToBottom()
 	} else {
@@ -482,7 +486,7 @@ func (app *App) handleSidebarClick(g *gocui.Gui, v *gocui.View) error {
 	}
 
 	_, cy := v.Cursor()
-	clickedItemIndex := cy
+	clickedItemIndex := app.sidebar.top + cy
 
 	// Ensure clicked item is within bounds
 	if clickedItemIndex < 0 || clickedItemIndex >= len(app.items) {
@@ -0,0 +1,568 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// =============================================================================
+// ACTION REGISTRY
+// =============================================================================
+
+// keyAction is a named command that can be bound to keys within one scope
+type keyAction struct {
+	scope       string // GLOBAL_SCOPE or a view name
+	name        string
+	description string
+	handler     func(*gocui.Gui, *gocui.View) error
+}
+
+// actions returns every bindable action, in the order they are bound and listed
+func (app *App) actions() []keyAction {
+	return []keyAction{
+		// Global actions
+		{GLOBAL_SCOPE, "quit", "Quit the application", app.quit},
+		{GLOBAL_SCOPE, "new_note", "Create a new note", app.newNote},
+		{GLOBAL_SCOPE, "new_folder", "Create a new folder", app.newFolder},
+		{GLOBAL_SCOPE, "refresh", "Reload the file list", app.refreshItems},
+		{GLOBAL_SCOPE, "toggle_sidebar", "Toggle the sidebar / switch panels", app.toggleSidebar},
+
+		// Sidebar actions
+		{SIDEBAR_VIEW, "cursor_up", "Select the previous item", app.cursorUp},
+		{SIDEBAR_VIEW, "cursor_down", "Select the next item", app.cursorDown},
+		{SIDEBAR_VIEW, "select_item", "Open the selected file or folder", app.selectItem},
+		{SIDEBAR_VIEW, "delete_item", "Delete the selected item", app.confirmDeleteItem},
+		{SIDEBAR_VIEW, "rename_item", "Rename the selected item", app.renameItem},
+		{SIDEBAR_VIEW, "external_edit", "Edit the selected note in $EDITOR", app.editInExternalEditor},
+		{SIDEBAR_VIEW, "jump", "Jump to an item by typing the start of its name", app.startTypeAhead},
+		{SIDEBAR_VIEW, "page_up", "Select the item a page up", app.sidebarPageUp},
+		{SIDEBAR_VIEW, "page_down", "Select the item a page down", app.sidebarPageDown},
+		{SIDEBAR_VIEW, "go_to_top", "Select the first item", app.sidebarTop},
+		{SIDEBAR_VIEW, "go_to_bottom", "Select the last item", app.sidebarBottom},
+
+		// Main view actions
+		{MAIN_VIEW, "edit", "Start editing / insert a new line", app.handleEnterInMainView},
+		{MAIN_VIEW, "exit_edit", "Leave edit mode (prompts to save)", app.handleEscInMainView},
+		{MAIN_VIEW, "save", "Save the current note", app.saveNote},
+		{MAIN_VIEW, "copy", "Copy the current line", app.copySelection},
+		{MAIN_VIEW, "paste", "Paste from the clipboard", app.pasteClipboard},
+		{MAIN_VIEW, "clipboard_history", "Paste one of the recent copies", app.clipboardHistory},
+		{MAIN_VIEW, "scroll_up", "Scroll / move the cursor up", app.handleScrollUp},
+		{MAIN_VIEW, "scroll_down", "Scroll / move the cursor down", app.handleScrollDown},
+		{MAIN_VIEW, "cursor_left", "Move the cursor left", app.handleCursorLeft},
+		{MAIN_VIEW, "cursor_right", "Move the cursor right", app.handleCursorRight},
+		{MAIN_VIEW, "scroll_left", "Scroll a large file left / move the cursor left", app.handleScrollLeft},
+		{MAIN_VIEW, "scroll_right", "Scroll a large file right / move the cursor right", app.handleScrollRight},
+		{MAIN_VIEW, "line_start", "Move the cursor to the start of the line", app.handleLineStart},
+		{MAIN_VIEW, "line_end", "Move the cursor to the end of the line", app.handleLineEnd},
+		{MAIN_VIEW, "page_up", "Scroll up a page", app.handlePageUp},
+		{MAIN_VIEW, "page_down", "Scroll down a page", app.handlePageDown},
+		{MAIN_VIEW, "go_to_top", "Go to the top of the note (row start in edit mode)", app.handleGoToTop},
+		{MAIN_VIEW, "go_to_bottom", "Go to the bottom of the note (row end in edit mode)", app.handleGoToBottom},
+		{MAIN_VIEW, "find", "Find (and replace) in the current note", app.openFind},
+		{MAIN_VIEW, "external_edit", "Edit the current note in $EDITOR", app.editInExternalEditor},
+		{MAIN_VIEW, "indent", "Indent the line or list item (edit mode)", app.handleIndent},
+		{MAIN_VIEW, "outdent", "Outdent the line or list item (edit mode)", app.handleOutdent},
+		{MAIN_VIEW, "bold", "Toggle **bold** on the selection or word", app.formatBold},
+		{MAIN_VIEW, "italic", "Toggle *italic* on the selection or word", app.formatItalic},
+		{MAIN_VIEW, "code", "Toggle `code` on the selection or word", app.formatCode},
+		{MAIN_VIEW, "strikethrough", "Toggle ~~strikethrough~~ on the selection or word", app.formatStrikethrough},
+		{MAIN_VIEW, "highlight", "Toggle ==highlight== on the selection or word", app.formatHighlight},
+		{MAIN_VIEW, "heading", "Cycle the heading level of the line", app.cycleHeading},
+		{MAIN_VIEW, "insert_link", "Insert a link to a note or URL", app.insertLink},
+		{MAIN_VIEW, "toggle_preview", "Toggle the live preview beside the editor", app.togglePreview},
+		{MAIN_VIEW, "line_numbers", "Toggle the line-number gutter", app.toggleLineNumbers},
+		{MAIN_VIEW, "go_to_line", "Go to a line number", app.goToLine},
+		{MAIN_VIEW, "spell_suggest", "Suggest spellings for the word under the cursor", app.spellSuggest},
+		{MAIN_VIEW, "wrap_line", "Show the top line of a large file in full", app.toggleWrapLine},
+		{MAIN_VIEW, "follow", "Follow the file as it grows (tail -f)", app.toggleFollow},
+		{MAIN_VIEW, "hexdump", "Show a binary file as a hexdump / summary", app.toggleHexdump},
+
+		// Find bar actions (find and replace fields)
+		{FIND_VIEW, "find_next", "Jump to the next match", app.findNext},
+		{FIND_VIEW, "find_prev", "Jump to the previous match", app.findPrev},
+		{FIND_VIEW, "find_close", "Close the find bar", app.closeFind},
+		{FIND_VIEW, "toggle_case", "Toggle case-sensitive matching", app.findToggleCase},
+		{FIND_VIEW, "toggle_word", "Toggle whole-word matching", app.findToggleWord},
+		{FIND_VIEW, "toggle_regex", "Toggle regular expression matching", app.findToggleRegex},
+		{FIND_VIEW, "switch_field", "Switch between find and replace fields", app.findSwitchField},
+		{FIND_VIEW, "replace_one", "Replace the current match", app.replaceOne},
+		{FIND_VIEW, "replace_all", "Replace every match", app.replaceAll},
+
+		// Picker actions (filter field and list)
+		{PICKER_VIEW, "picker_next", "Select the next entry", app.pickerNext},
+		{PICKER_VIEW, "picker_prev", "Select the previous entry", app.pickerPrev},
+		{PICKER_VIEW, "picker_pick", "Choose the selected entry", app.pickerPick},
+		{PICKER_VIEW, "picker_close", "Close the picker", app.pickerClose},
+
+		// Input dialog actions
+		{INPUT_VIEW, "confirm", "Confirm the dialog", app.handleDialogConfirm},
+		{INPUT_VIEW, "cancel", "Cancel the dialog", app.handleDialogCancel},
+	}
+}
+
+// overlayScopes are modal views that intentionally capture global keys while focused
+var overlayScopes = map[string]bool{
+	INPUT_VIEW:  true,
+	FIND_VIEW:   true,
+	PICKER_VIEW: true,
+}
+
+// editFallbacks are main view actions that run a global action outside edit mode,
+// so sharing its key is not a conflict
+var editFallbacks = map[string]string{
+	"indent": "toggle_sidebar",
+}
+
+// scopeViews returns the gocui view names a scope's bindings are registered on
+func scopeViews(scope string) []string {
+	switch scope {
+	case GLOBAL_SCOPE:
+		return []string{""}
+	case FIND_VIEW:
+		return []string{FIND_VIEW, REPLACE_VIEW}
+	case PICKER_VIEW:
+		return []string{PICKER_VIEW, PICKER_INPUT_VIEW}
+	}
+	return []string{scope}
+}
+
+// findAction looks up an action by scope and name
+func (app *App) findAction(scope, name string) *keyAction {
+	for _, action := range app.actions() {
+		if action.scope == scope && action.name == name {
+			return &action
+		}
+	}
+	return nil
+}
+
+// =============================================================================
+// KEYMAP PROFILES
+// =============================================================================
+
+// Keymap maps a scope to action names and the keys bound to them
+type Keymap map[string]map[string][]string
+
+// keymapFile is the on-disk keymap format
+type keymapFile struct {
+	Profile  string `json:"profile"`  // base profile ("default" or "emacs")
+	Bindings Keymap `json:"bindings"` // per-action overrides of the profile
+}
+
+// keymapProfiles lists the built-in keymap profiles
+var keymapProfiles = map[string]func() Keymap{
+	"default": defaultKeymap,
+	"emacs":   emacsKeymap,
+}
+
+// defaultKeymap returns the built-in default key bindings
+func defaultKeymap() Keymap {
+	return Keymap{
+		GLOBAL_SCOPE: {
+			"quit":           {"Ctrl+Q"},
+			"new_note":       {"Ctrl+N"},
+			"new_folder":     {"Ctrl+F"},
+			"refresh":        {"F5", "Ctrl+R"},
+			"toggle_sidebar": {"Tab"},
+		},
+		SIDEBAR_VIEW: {
+			"cursor_up":     {"Up"},
+			"cursor_down":   {"Down"},
+			"select_item":   {"Enter"},
+			"delete_item":   {"d"},
+			"rename_item":   {"r"},
+			"external_edit": {"Ctrl+E"},
+			"jump":          {"/"},
+			"page_up":       {"PgUp"},
+			"page_down":     {"PgDn"},
+			"go_to_top":     {"Home"},
+			"go_to_bottom":  {"End"},
+		},
+		MAIN_VIEW: {
+			"edit":              {"Enter"},
+			"exit_edit":         {"Esc"},
+			"save":              {"Ctrl+S"},
+			"copy":              {"Ctrl+C"},
+			"paste":             {"Ctrl+V"},
+			"clipboard_history": {"Alt+v"},
+			"scroll_up":         {"Up"},
+			"scroll_down":       {"Down"},
+			"cursor_left":       {},
+			"cursor_right":      {},
+			"line_start":        {},
+			"line_end":          {},
+			"page_up":           {"PgUp"},
+			"page_down":         {"PgDn"},
+			"go_to_top":         {"Home"},
+			"go_to_bottom":      {"End"},
+			"find":              {"F3", "/"},
+			"external_edit":     {"Ctrl+E"},
+			"indent":            {"Tab"},
+			"outdent":           {"Shift+Tab"},
+			"bold":              {"Ctrl+B"},
+			"italic":            {"Alt+i"},
+			"code":              {"Alt+c"},
+			"strikethrough":     {"Alt+s"},
+			"highlight":         {"Alt+m"},
+			"heading":           {"Alt+h"},
+			"insert_link":       {"Ctrl+K"},
+			"toggle_preview":    {"F6"},
+			"line_numbers":      {"F7"},
+			"spell_suggest":     {"F8"},
+			"go_to_line":        {"Ctrl+G"},
+			"scroll_left":       {"Left"},
+			"scroll_right":      {"Right"},
+			"wrap_line":         {"Alt+w"},
+			"follow":            {"Alt+f"},
+			"hexdump":           {"Alt+x"},
+		},
+		FIND_VIEW: {
+			"find_next":    {"Enter", "Down"},
+			"find_prev":    {"Up"},
+			"find_close":   {"Esc"},
+			"toggle_case":  {"Alt+c"},
+			"toggle_word":  {"Alt+w"},
+			"toggle_regex": {"Alt+x"},
+			"switch_field": {"Tab"},
+			"replace_one":  {"Alt+r"},
+			"replace_all":  {"Alt+a"},
+		},
+		PICKER_VIEW: {
+			"picker_next":  {"Down", "Ctrl+N"},
+			"picker_prev":  {"Up", "Ctrl+P"},
+			"picker_pick":  {"Enter"},
+			"picker_close": {"Esc"},
+		},
+		INPUT_VIEW: {
+			"confirm": {"Enter"},
+			"cancel":  {"Esc"},
+		},
+	}
+}
+
+// emacsKeymap returns Emacs-style bindings layered over the defaults
+func emacsKeymap() Keymap {
+	km := defaultKeymap()
+
+	km[GLOBAL_SCOPE]["new_note"] = []string{"Ctrl+T"}
+	km[GLOBAL_SCOPE]["new_folder"] = []string{"Ctrl+O"}
+
+	km[SIDEBAR_VIEW]["cursor_up"] = []string{"Up", "Ctrl+P"}
+	km[SIDEBAR_VIEW]["cursor_down"] = []string{"Down", "Ctrl+N"}
+	km[SIDEBAR_VIEW]["external_edit"] = []string{"F4"}
+
+	km[MAIN_VIEW]["exit_edit"] = []string{"Esc", "Ctrl+G"}
+	km[MAIN_VIEW]["copy"] = []string{"Ctrl+W"}
+	km[MAIN_VIEW]["paste"] = []string{"Ctrl+Y"}
+	km[MAIN_VIEW]["clipboard_history"] = []string{"Alt+y"}
+	km[MAIN_VIEW]["scroll_up"] = []string{"Up", "Ctrl+P"}
+	km[MAIN_VIEW]["scroll_down"] = []string{"Down", "Ctrl+N"}
+	km[MAIN_VIEW]["cursor_left"] = []string{"Ctrl+B"}
+	km[MAIN_VIEW]["cursor_right"] = []string{"Ctrl+F"}
+	km[MAIN_VIEW]["line_start"] = []string{"Ctrl+A"}
+	km[MAIN_VIEW]["line_end"] = []string{"Ctrl+E"}
+	km[MAIN_VIEW]["page_down"] = []string{"PgDn", "Ctrl+V"}
+	km[MAIN_VIEW]["external_edit"] = []string{"F4"}
+	km[MAIN_VIEW]["bold"] = []string{"Alt+b"}
+	km[MAIN_VIEW]["go_to_line"] = []string{"Alt+g"}
+
+	return km
+}
+
+// defaultKeymapPath returns the keymap file location in the user config directory
+func defaultKeymapPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return KEYMAP_FILE
+	}
+	return filepath.Join(dir, "cui-notes", KEYMAP_FILE)
+}
+
+// loadKeymap loads the keymap file (if any) on top of its profile and checks it for conflicts
+func (app *App) loadKeymap(path string) error {
+	file := keymapFile{Profile: "default"}
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err == nil {
+		if err := json.Unmarshal(data, &file); err != nil {
+			return fmt.Errorf("keymap %s: %v", path, err)
+		}
+	}
+
+	profile, exists := keymapProfiles[file.Profile]
+	if !exists {
+		return fmt.Errorf("keymap %s: unknown profile %q", path, file.Profile)
+	}
+	km := profile()
+
+	for scope, bindings := range file.Bindings {
+		for name, keys := range bindings {
+			if app.findAction(scope, name) == nil {
+				return fmt.Errorf("keymap %s: unknown action %q in %q", path, name, scope)
+			}
+			for _, key := range keys {
+				if _, err := parseKey(key); err != nil {
+					return fmt.Errorf("keymap %s: %s.%s: %v", path, scope, name, err)
+				}
+			}
+			km[scope][name] = keys
+		}
+	}
+
+	if conflicts := app.keymapConflicts(km); len(conflicts) > 0 {
+		return fmt.Errorf("keymap %s has conflicting bindings:\n  %s", path, strings.Join(conflicts, "\n  "))
+	}
+
+	app.keymap = km
+	return nil
+}
+
+// keymapConflicts reports keys bound to more than one action in the same scope,
+// and panel keys that shadow a global binding
+func (app *App) keymapConflicts(km Keymap) []string {
+	bound := make(map[string]map[keySpec]string)
+	var conflicts []string
+
+	for _, action := range app.actions() {
+		if bound[action.scope] == nil {
+			bound[action.scope] = make(map[keySpec]string)
+		}
+		for _, name := range km[action.scope][action.name] {
+			spec, err := parseKey(name)
+			if err != nil {
+				conflicts = append(conflicts, fmt.Sprintf("%s.%s: %v", action.scope, action.name, err))
+				continue
+			}
+			if other, exists := bound[action.scope][spec]; exists && other != action.name {
+				conflicts = append(conflicts, fmt.Sprintf("%s: %s is bound to both %s and %s",
+					action.scope, spec, other, action.name))
+			}
+			bound[action.scope][spec] = action.name
+		}
+	}
+
+	for scope, keys := range bound {
+		if scope == GLOBAL_SCOPE || overlayScopes[scope] {
+			continue
+		}
+		for spec, name := range keys {
+			if global, exists := bound[GLOBAL_SCOPE][spec]; exists && editFallbacks[name] != global {
+				conflicts = append(conflicts, fmt.Sprintf("%s: %s (%s) shadows global %s",
+					scope, spec, name, global))
+			}
+		}
+	}
+
+	sort.Strings(conflicts)
+	return conflicts
+}
+
+// bindKeymap registers every action's keys with gocui
+func (app *App) bindKeymap() error {
+	for _, action := range app.actions() {
+		for _, name := range app.keymap[action.scope][action.name] {
+			spec, err := parseKey(name)
+			if err != nil {
+				return err
+			}
+			for _, viewName := range scopeViews(action.scope) {
+				if err := app.gui.SetKeybinding(viewName, spec.binding(), spec.mod, action.handler); err != nil {
+					return err
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// runRuneBinding runs the action bound to a modified rune key such as Alt+i.
+// gocui skips rune bindings on editable views, so their editors dispatch them here.
+func (app *App) runRuneBinding(scope string, v *gocui.View, ch rune, mod gocui.Modifier) bool {
+	for _, action := range app.actions() {
+		if action.scope != scope {
+			continue
+		}
+		for _, name := range app.keymap[scope][action.name] {
+			if spec, err := parseKey(name); err == nil && spec.ch == ch && spec.mod == mod {
+				action.handler(app.gui, v)
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// =============================================================================
+// HELP TEXT
+// =============================================================================
+
+// keyHint returns the keys bound to an action for display, e.g. "F5/Ctrl+R"
+func (app *App) keyHint(scope, action string) string {
+	var names []string
+	for _, name := range app.keymap[scope][action] {
+		if spec, err := parseKey(name); err == nil {
+			names = append(names, spec.String())
+		}
+	}
+	return strings.Join(names, "/")
+}
+
+// keyHintSpec describes one "keys: label" entry of generated help text
+type keyHintSpec struct {
+	scope   string
+	actions []string
+	label   string
+}
+
+// formatKeyHints renders "keys: label" entries joined by sep, skipping unbound actions
+func (app *App) formatKeyHints(sep string, hints ...keyHintSpec) string {
+	var parts []string
+	for _, hint := range hints {
+		var keys []string
+		for _, action := range hint.actions {
+			if key := app.keyHint(hint.scope, action); key != "" {
+				keys = append(keys, key)
+			}
+		}
+		if len(keys) > 0 {
+			parts = append(parts, strings.Join(keys, "/")+": "+hint.label)
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// keymapHelp returns a table of every action and its bound keys
+func (app *App) keymapHelp() string {
+	var b strings.Builder
+	scope := ""
+	for _, action := range app.actions() {
+		if action.scope != scope {
+			scope = action.scope
+			fmt.Fprintf(&b, "\n[%s]\n", scope)
+		}
+		keys := app.keyHint(action.scope, action.name)
+		if keys == "" {
+			keys = "(unbound)"
+		}
+		fmt.Fprintf(&b, "  %-16s %-14s %s\n", keys, action.name, action.description)
+	}
+	return strings.TrimPrefix(b.String(), "\n")
+}
+
+// =============================================================================
+// KEY PARSING
+// =============================================================================
+
+// keySpec is a parsed key: either a special key or a rune, plus modifier
+type keySpec struct {
+	key gocui.Key
+	ch  rune
+	mod gocui.Modifier
+}
+
+// namedKeys maps key names (lower case) to gocui keys
+var namedKeys = map[string]gocui.Key{
+	"f1": gocui.KeyF1, "f2": gocui.KeyF2, "f3": gocui.KeyF3, "f4": gocui.KeyF4,
+	"f5": gocui.KeyF5, "f6": gocui.KeyF6, "f7": gocui.KeyF7, "f8": gocui.KeyF8,
+	"f9": gocui.KeyF9, "f10": gocui.KeyF10, "f11": gocui.KeyF11, "f12": gocui.KeyF12,
+	"insert": gocui.KeyInsert, "delete": gocui.KeyDelete,
+	"home": gocui.KeyHome, "end": gocui.KeyEnd,
+	"pgup": gocui.KeyPgup, "pgdn": gocui.KeyPgdn,
+	"up": gocui.KeyArrowUp, "down": gocui.KeyArrowDown,
+	"left": gocui.KeyArrowLeft, "right": gocui.KeyArrowRight,
+	"↑": gocui.KeyArrowUp, "↓": gocui.KeyArrowDown,
+	"←": gocui.KeyArrowLeft, "→": gocui.KeyArrowRight,
+	"tab": gocui.KeyTab, "backtab": gocui.KeyBacktab,
+	"enter": gocui.KeyEnter, "esc": gocui.KeyEsc, "space": gocui.KeySpace,
+	"backspace": gocui.KeyBackspace2,
+}
+
+// keyDisplayNames gives the preferred display name for special keys
+var keyDisplayNames = map[gocui.Key]string{
+	gocui.KeyF1: "F1", gocui.KeyF2: "F2", gocui.KeyF3: "F3", gocui.KeyF4: "F4",
+	gocui.KeyF5: "F5", gocui.KeyF6: "F6", gocui.KeyF7: "F7", gocui.KeyF8: "F8",
+	gocui.KeyF9: "F9", gocui.KeyF10: "F10", gocui.KeyF11: "F11", gocui.KeyF12: "F12",
+	gocui.KeyInsert: "Insert", gocui.KeyDelete: "Delete",
+	gocui.KeyHome: "Home", gocui.KeyEnd: "End",
+	gocui.KeyPgup: "PgUp", gocui.KeyPgdn: "PgDn",
+	gocui.KeyArrowUp: "↑", gocui.KeyArrowDown: "↓",
+	gocui.KeyArrowLeft: "←", gocui.KeyArrowRight: "→",
+	gocui.KeyTab: "Tab", gocui.KeyBacktab: "Shift+Tab",
+	gocui.KeyEnter: "Enter", gocui.KeyEsc: "Esc", gocui.KeySpace: "Space",
+	gocui.KeyBackspace2: "Backspace",
+}
+
+// parseKey parses key names such as "Ctrl+S", "F5", "Alt+x", "PgDn" or "d"
+func parseKey(name string) (keySpec, error) {
+	var spec keySpec
+	rest := name
+
+	for {
+		lower := strings.ToLower(rest)
+		switch {
+		case strings.HasPrefix(lower, "alt+") && len(rest) > 4:
+			spec.mod = gocui.ModAlt
+			rest = rest[4:]
+			continue
+		case strings.HasPrefix(lower, "ctrl+") && len(rest) > 5:
+			r, size := utf8.DecodeRuneInString(strings.ToLower(rest[5:]))
+			if size != len(rest)-5 || r < 'a' || r > 'z' {
+				return spec, fmt.Errorf("unsupported key %q", name)
+			}
+			spec.key = gocui.Key(int(gocui.KeyCtrlA) + int(r-'a'))
+			return spec, nil
+		case lower == "shift+tab":
+			spec.key = gocui.KeyBacktab
+			return spec, nil
+		}
+		break
+	}
+
+	if key, exists := namedKeys[strings.ToLower(rest)]; exists {
+		spec.key = key
+		return spec, nil
+	}
+	if r, size := utf8.DecodeRuneInString(rest); size > 0 && size == len(rest) {
+		spec.ch = r
+		return spec, nil
+	}
+	return spec, fmt.Errorf("unknown key %q", name)
+}
+
+// binding returns the value gocui expects for SetKeybinding
+func (k keySpec) binding() interface{} {
+	if k.ch != 0 {
+		return k.ch
+	}
+	return k.key
+}
+
+// String returns the display name of the key
+func (k keySpec) String() string {
+	prefix := ""
+	if k.mod == gocui.ModAlt {
+		prefix = "Alt+"
+	}
+	if k.ch != 0 {
+		return prefix + string(k.ch)
+	}
+	if name, exists := keyDisplayNames[k.key]; exists {
+		return prefix + name
+	}
+	if k.key >= gocui.KeyCtrlA && k.key <= gocui.KeyCtrlZ {
+		return prefix + "Ctrl+" + string(rune('A'+int(k.key-gocui.KeyCtrlA)))
+	}
+	return fmt.Sprintf("%sKey(%d)", prefix, k.key)
+}
@@ -0,0 +1,455 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// =============================================================================
+// PIECE TABLE
+// =============================================================================
+
+// linePiece is a run of document lines taken from the file or from the added lines
+type linePiece struct {
+	added bool // lines come from pieceTable.added rather than the file
+	start int  // first line in the file or in pieceTable.added
+	count int
+}
+
+// pieceTable describes an edited large file as runs of original file lines and
+// edited lines kept in memory, so the file itself is never loaded whole
+type pieceTable struct {
+	pieces []linePiece
+	added  []string // edited lines, append only
+	total  int      // lines in the document
+}
+
+// newPieceTable returns a table for an unedited file of fileLines lines
+func newPieceTable(fileLines int) *pieceTable {
+	pt := &pieceTable{total: fileLines}
+	if fileLines > 0 {
+		pt.pieces = []linePiece{{start: 0, count: fileLines}}
+	}
+	return pt
+}
+
+// clone returns a copy of the table that can be edited on its own
+func (pt *pieceTable) clone() *pieceTable {
+	return &pieceTable{
+		pieces: append([]linePiece(nil), pt.pieces...),
+		added:  pt.added[:len(pt.added):len(pt.added)], // Appends copy
+		total:  pt.total,
+	}
+}
+
+// split makes line a piece boundary and returns the index of the piece starting there
+func (pt *pieceTable) split(line int) int {
+	pos := 0
+	for i, p := range pt.pieces {
+		if line == pos {
+			return i
+		}
+		if line < pos+p.count {
+			off := line - pos
+			left := linePiece{added: p.added, start: p.start, count: off}
+			right := linePiece{added: p.added, start: p.start + off, count: p.count - off}
+			pt.pieces = append(pt.pieces[:i], append([]linePiece{left, right}, pt.pieces[i+1:]...)...)
+			return i + 1
+		}
+		pos += p.count
+	}
+	return len(pt.pieces)
+}
+
+// replace replaces n document lines starting at line with lines
+func (pt *pieceTable) replace(line, n int, lines []string) {
+	first := pt.split(line)
+	last := pt.split(line + n)
+
+	var middle []linePiece
+	if len(lines) > 0 {
+		middle = []linePiece{{added: true, start: len(pt.added), count: len(lines)}}
+		pt.added = append(pt.added, lines...)
+	}
+	pt.pieces = append(pt.pieces[:first], append(middle, pt.pieces[last:]...)...)
+	pt.total += len(lines) - n
+}
+
+// lines returns document lines [from, to), reading file lines through fetch
+func (pt *pieceTable) lines(from, to int, fetch func(start, end int) ([]string, error)) ([]string, error) {
+	var out []string
+	pos := 0
+	for _, p := range pt.pieces {
+		if pos >= to {
+			break
+		}
+		end := pos + p.count
+		if end > from {
+			s, e := from-pos, to-pos
+			if s < 0 {
+				s = 0
+			}
+			if e > p.count {
+				e = p.count
+			}
+			if p.added {
+				out = append(out, pt.added[p.start+s:p.start+e]...)
+			} else {
+				fileLines, err := fetch(p.start+s, p.start+e)
+				if err != nil {
+					return nil, err
+				}
+				out = append(out, fileLines...)
+			}
+		}
+		pos = end
+	}
+	return out, nil
+}
+
+// =============================================================================
+// LARGE FILE EDITING
+// =============================================================================
+
+// largeEditState tracks editing of a file above LARGE_FILE_THRESHOLD. The main
+// view holds a window of the document; edits are folded into the piece table
+// whenever the window moves, and saving streams the table back to disk.
+type largeEditState struct {
+	table    *pieceTable
+	winStart int    // first document line in the main view
+	winLen   int    // document lines the main view held when the window was loaded
+	winText  string // main view content when the window was loaded
+	modified bool   // edits have been folded into the table
+	newline  string // line ending of the file
+	trailing bool   // the file ends with a line ending
+}
+
+// fileLines returns original file lines [start, end) through the line cache
+func (app *App) fileLines(start, end int) ([]string, error) {
+	if err := app.ensureLinesInCache(start, end); err != nil {
+		return nil, err
+	}
+	lo, hi := start-app.cacheStartLine, end-app.cacheStartLine
+	if hi > len(app.lineCache) {
+		hi = len(app.lineCache)
+	}
+	if lo < 0 || lo > hi {
+		return nil, fmt.Errorf("lines %d-%d not cached", start, end)
+	}
+	return append([]string(nil), app.lineCache[lo:hi]...), nil
+}
+
+// enterLargeEditMode loads the window around the current line into the main view
+func (app *App) enterLargeEditMode(v *gocui.View) error {
+	newline, trailing := fileLineEndings(app.fileHandle, app.fileSize)
+	app.largeEdit = &largeEditState{
+		table:    newPieceTable(app.totalLines),
+		newline:  newline,
+		trailing: trailing,
+	}
+
+	start := app.currentLine - LARGE_EDIT_WINDOW/4
+	if start < 0 {
+		start = 0
+	}
+	return app.loadEditWindow(v, start, app.currentLine, 0)
+}
+
+// loadEditWindow fills the main view with the window starting at document line
+// start, with the cursor on line cursorLine shown screenRow rows from the top
+func (app *App) loadEditWindow(v *gocui.View, start, cursorLine, screenRow int) error {
+	le := app.largeEdit
+	end := start + LARGE_EDIT_WINDOW
+	if end > le.table.total {
+		end = le.table.total
+	}
+	lines, err := le.table.lines(start, end, app.fileLines)
+	if err != nil {
+		return err
+	}
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+
+	le.winStart = start
+	le.winLen = end - start
+	le.winText = strings.Join(lines, "\n")
+
+	v.Clear()
+	fmt.Fprint(v, le.winText)
+
+	y := cursorLine - start
+	if y < 0 {
+		y = 0
+	}
+	if y >= len(lines) {
+		y = len(lines) - 1
+	}
+
+	// Put the cursor line screenRow rows below the top of the view
+	width, _ := v.Size()
+	origin := lineRow(lines, y, width) - screenRow
+	if origin < 0 {
+		origin = 0
+	}
+	v.SetOrigin(0, origin)
+	v.SetCursor(0, y)
+	v.MoveCursor(0, 0)
+	app.scheduleSpellCheck()
+	return nil
+}
+
+// commitEditWindow folds edits in the main view into the piece table
+func (app *App) commitEditWindow(v *gocui.View) {
+	le := app.largeEdit
+	lines := v.BufferLines()
+	text := strings.Join(lines, "\n")
+	if text == le.winText {
+		return
+	}
+	le.table.replace(le.winStart, le.winLen, lines)
+	le.winLen = len(lines)
+	le.winText = text
+	le.modified = true
+}
+
+// largeEditLines returns the number of document lines including unfolded edits
+func (app *App) largeEditLines(v *gocui.View) int {
+	le := app.largeEdit
+	return le.table.total - le.winLen + len(v.BufferLines())
+}
+
+// largeEditLine returns the document line of the cursor
+func (app *App) largeEditLine(v *gocui.View) int {
+	_, cy := v.Cursor()
+	return app.largeEdit.winStart + cy
+}
+
+// moveEditWindow moves the window so document line target is loaded and puts
+// the cursor on it, screenRow rows from the top of the view
+func (app *App) moveEditWindow(v *gocui.View, target, screenRow int) error {
+	app.commitEditWindow(v)
+	le := app.largeEdit
+	if target >= le.table.total {
+		target = le.table.total - 1
+	}
+	if target < 0 {
+		target = 0
+	}
+
+	// Within the window just move the cursor
+	if target >= le.winStart && target < le.winStart+le.winLen {
+		cx, _ := v.Cursor()
+		v.SetCursor(cx, target-le.winStart)
+		v.MoveCursor(0, 0)
+		return nil
+	}
+
+	start := target - LARGE_EDIT_WINDOW/2
+	if start < 0 {
+		start = 0
+	}
+	return app.loadEditWindow(v, start, target, screenRow)
+}
+
+// largeEditEdge pages in the neighbouring window when the cursor is about to
+// leave the loaded one. It reports whether it handled the movement.
+func (app *App) largeEditEdge(v *gocui.View, dir int) bool {
+	le := app.largeEdit
+	if le == nil {
+		return false
+	}
+	_, cy := v.Cursor()
+	_, height := v.Size()
+
+	if dir < 0 && cy == 0 && le.winStart > 0 {
+		app.moveEditWindow(v, le.winStart-1, 0)
+		return true
+	}
+	if dir > 0 && cy >= len(v.BufferLines())-1 && app.largeEditLine(v)+1 < app.largeEditLines(v) {
+		app.moveEditWindow(v, app.largeEditLine(v)+1, height-1)
+		return true
+	}
+	return false
+}
+
+// largeEditPage moves the cursor a screen up (dir < 0) or down (dir > 0)
+func (app *App) largeEditPage(v *gocui.View, dir int) error {
+	_, height := v.Size()
+	_, row := cursorScreenPos(v)
+	return app.moveEditWindow(v, app.largeEditLine(v)+dir*height, row)
+}
+
+// largeEditModified reports whether the large file has unsaved edits
+func (app *App) largeEditModified(v *gocui.View) bool {
+	le := app.largeEdit
+	return le.modified || strings.Join(v.BufferLines(), "\n") != le.winText
+}
+
+// saveLargeFile writes the edited document back to disk and reloads the window
+func (app *App) saveLargeFile(v *gocui.View, notePath string) error {
+	le := app.largeEdit
+	app.commitEditWindow(v)
+	if le.modified {
+		if err := app.writeLargeFile(notePath); err != nil {
+			return err
+		}
+	}
+
+	// The file changed underneath the line cache, so start over from disk
+	info, err := os.Stat(notePath)
+	if err != nil {
+		return err
+	}
+	if err := app.openFileForReading(notePath); err != nil {
+		return err
+	}
+	app.fileSize = info.Size()
+	app.lineCache = nil
+	app.cacheStartLine = -1
+	app.cacheEndLine = -1
+
+	// The saved file has the document's lines; its index is rebuilt in the
+	// background and indexFinished updates totalLines
+	lines := le.table.total
+	if err := app.startLineIndexing(notePath); err != nil {
+		return err
+	}
+
+	cx, cy := v.Cursor()
+	_, row := cursorScreenPos(v)
+	le.table = newPieceTable(lines)
+	le.modified = false
+	if err := app.loadEditWindow(v, le.winStart, le.winStart+cy, row); err != nil {
+		return err
+	}
+	_, cy = v.Cursor()
+	v.SetCursor(cx, cy)
+	return nil
+}
+
+// writeLargeFile streams the piece table into a temporary file next to the
+// note and renames it into place
+func (app *App) writeLargeFile(notePath string) error {
+	le := app.largeEdit
+	return writePieceTable(notePath, le.table, le.newline, le.trailing)
+}
+
+// writePieceTable rewrites notePath as the document table describes over it
+func writePieceTable(notePath string, table *pieceTable, newline string, trailing bool) error {
+	src, err := os.Open(notePath)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+	info, err := src.Stat()
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(notePath), "."+filepath.Base(notePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name()) // Fails harmlessly once renamed
+
+	reader := bufio.NewReader(src)
+	w := bufio.NewWriter(tmp)
+	fileLine := 0
+	first := true
+	writeLine := func(line string) {
+		if !first {
+			w.WriteString(newline)
+		}
+		first = false
+		w.WriteString(line)
+	}
+
+	for _, p := range table.pieces {
+		if p.added {
+			for _, line := range table.added[p.start : p.start+p.count] {
+				writeLine(line)
+			}
+			continue
+		}
+
+		// Edits only replace lines, so file pieces stay in file order
+		if p.start < fileLine {
+			tmp.Close()
+			return fmt.Errorf("piece table out of order at line %d", p.start)
+		}
+		for ; fileLine < p.start; fileLine++ {
+			if _, err := readFileLine(reader); err != nil {
+				tmp.Close()
+				return err
+			}
+		}
+		for i := 0; i < p.count; i++ {
+			line, err := readFileLine(reader)
+			if err != nil {
+				tmp.Close()
+				return err
+			}
+			writeLine(line)
+			fileLine++
+		}
+	}
+	if trailing && !first {
+		w.WriteString(newline)
+	}
+
+	if err := w.Flush(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	os.Chmod(tmp.Name(), info.Mode().Perm())
+	return os.Rename(tmp.Name(), notePath)
+}
+
+// readFileLine reads one line without its line ending
+func readFileLine(r *bufio.Reader) (string, error) {
+	line, err := r.ReadString('\n')
+	if err == io.EOF && line != "" {
+		err = nil
+	}
+	if err != nil {
+		return "", err
+	}
+	line = strings.TrimSuffix(line, "\n")
+	return strings.TrimSuffix(line, "\r"), nil
+}
+
+// fileLineEndings reports the line ending a file uses and whether it ends with one
+func fileLineEndings(file io.ReaderAt, size int64) (string, bool) {
+	newline := "\n"
+	head := make([]byte, DEFAULT_CHUNK_SIZE)
+	if n, _ := file.ReadAt(head, 0); n > 0 {
+		if i := bytes.IndexByte(head[:n], '\n'); i > 0 && head[i-1] == '\r' {
+			newline = "\r\n"
+		}
+	}
+
+	last := make([]byte, 1)
+	trailing := false
+	if size > 0 {
+		if _, err := file.ReadAt(last, size-1); err == nil {
+			trailing = last[0] == '\n'
+		}
+	}
+	return newline, trailing
+}
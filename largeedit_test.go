@@ -0,0 +1,51 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSaveLargeFileIndexesInBackground(t *testing.T) {
+	const lines = 30000
+	app := newTestApp(t, map[string]string{"big.log": numberedLines(0, lines)})
+	selectFile(t, app, "big.log")
+	drainUntil(t, app, func() bool { return app.indexJob == nil })
+
+	v, err := app.gui.View(MAIN_VIEW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.enterEditMode(app.gui, v); err != nil || app.largeEdit == nil {
+		t.Fatalf("large edit mode not entered: %v", err)
+	}
+
+	// Add a line at the top of the window and save
+	v.SetCursor(0, 0)
+	v.EditNewLine()
+	if err := app.saveNote(app.gui, v); err != nil {
+		t.Fatal(err)
+	}
+	if app.indexJob == nil {
+		t.Fatal("saving did not start indexing in the background")
+	}
+	if app.largeEdit.table.total != lines+1 {
+		t.Fatalf("document has %d lines after saving, want %d", app.largeEdit.table.total, lines+1)
+	}
+
+	drainUntil(t, app, func() bool { return app.indexJob == nil })
+	if app.totalLines != lines+1 {
+		t.Fatalf("totalLines = %d, want %d", app.totalLines, lines+1)
+	}
+	data, err := os.ReadFile(filepath.Join(app.notesDir, "big.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "\n" + numberedLines(0, lines); string(data) != want {
+		t.Fatalf("saved %d bytes, want %d", len(data), len(want))
+	}
+	if !strings.HasPrefix(strings.Join(v.BufferLines(), "\n"), "\nline 0 of") {
+		t.Errorf("window not reloaded from the saved file")
+	}
+}
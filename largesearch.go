@@ -0,0 +1,209 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// =============================================================================
+// LARGE FILE SEARCH
+// =============================================================================
+
+// largeSearch is a scan of a large file for the find query. It runs in the
+// background and reports the lines with matches in batches as it goes.
+type largeSearch struct {
+	pattern *regexp.Regexp
+	cancel  chan struct{}
+	lines   []int // lines with matches, in file order
+	before  []int // matches on the lines before each of lines
+	total   int   // matches found so far
+	done    bool  // the whole file has been scanned
+	pending int   // step (1 or -1) waiting for the scan to find a match
+}
+
+// step returns the index into lines of the next (dir > 0) or previous match
+// from line from, wrapping around once the scan is done
+func (s *largeSearch) step(from, dir int) (int, bool) {
+	if dir > 0 {
+		if i := sort.SearchInts(s.lines, from+1); i < len(s.lines) {
+			return i, true
+		}
+		return 0, s.done && len(s.lines) > 0
+	}
+	if i := sort.SearchInts(s.lines, from) - 1; i >= 0 {
+		return i, true
+	}
+	return len(s.lines) - 1, s.done && len(s.lines) > 0
+}
+
+// status returns the match count shown in the find bar, with the position of
+// the match on line when there is one
+func (s *largeSearch) status(line int) string {
+	more := ""
+	if !s.done {
+		more = "+"
+	}
+	if s.total == 0 {
+		if s.done {
+			return "No matches"
+		}
+		return "Searching…"
+	}
+	if i := sort.SearchInts(s.lines, line); i < len(s.lines) && s.lines[i] == line {
+		return fmt.Sprintf("%d/%d%s", s.before[i]+1, s.total, more)
+	}
+	return fmt.Sprintf("%d matches%s", s.total, more)
+}
+
+// startLargeSearch scans the open large file for the find pattern in the
+// background, replacing any scan already running
+func (app *App) startLargeSearch() {
+	app.cancelLargeSearch()
+	f := &app.find
+	if f.pattern == nil || app.fileHandle == nil {
+		return
+	}
+
+	s := &largeSearch{pattern: f.pattern, cancel: make(chan struct{})}
+	f.large = s
+	path := app.fileHandle.Name()
+	mapped := app.useMapping()
+
+	go func() {
+		var lines, counts []int
+		flush := func(done bool) {
+			app.events.post(searchBatchMsg{s, lines, counts, done})
+			lines, counts = nil, nil
+		}
+
+		file, err := openLargeFile(path, mapped)
+		if err != nil {
+			flush(true)
+			return
+		}
+		defer file.Close()
+
+		// Lines are matched as rendered, like the viewport shows them
+		reader := file.lines(0)
+		lastFlush := time.Now()
+		for lineNum := 0; ; lineNum++ {
+			select {
+			case <-s.cancel:
+				return
+			default:
+			}
+
+			line, _, err := reader.segment(0, -1)
+			if err != nil {
+				break
+			}
+			count := 0
+			for _, loc := range s.pattern.FindAllStringIndex(app.renderMarkdownLine(line), -1) {
+				if loc[0] != loc[1] {
+					count++
+				}
+			}
+			if count > 0 {
+				lines = append(lines, lineNum)
+				counts = append(counts, count)
+			}
+
+			if time.Since(lastFlush) >= INDEX_PROGRESS_MS*time.Millisecond {
+				flush(false)
+				lastFlush = time.Now()
+			}
+		}
+		flush(true)
+	}()
+}
+
+// addSearchResults records a batch of matching lines from a scan that is
+// still current, and takes a step that was waiting for one
+func (app *App) addSearchResults(s *largeSearch, lines, counts []int, done bool) error {
+	if app.find.large != s {
+		return nil // Cancelled or replaced by a newer query
+	}
+	for i, line := range lines {
+		s.lines = append(s.lines, line)
+		s.before = append(s.before, s.total)
+		s.total += counts[i]
+	}
+	s.done = done
+
+	if dir := s.pending; dir != 0 && (len(lines) > 0 || done) {
+		s.pending = 0
+		return app.findStepLargeFile(dir)
+	}
+	app.updateFindTitles()
+	return nil
+}
+
+// cancelLargeSearch stops the scan of a large file, if one is running
+func (app *App) cancelLargeSearch() {
+	if s := app.find.large; s != nil {
+		close(s.cancel)
+		app.find.large = nil
+	}
+}
+
+// findStepLargeFile moves to the next (dir > 0) or previous line with a match
+// and scrolls the viewport to it
+func (app *App) findStepLargeFile(dir int) error {
+	f := &app.find
+	s := f.large
+	if s == nil {
+		return nil
+	}
+
+	from := f.largeLine
+	if from < 0 {
+		from = app.currentLine - 1
+		if dir < 0 {
+			from = app.currentLine
+		}
+	}
+
+	i, found := s.step(from, dir)
+	if !found {
+		if !s.done {
+			s.pending = dir // Step once the scan gets there
+			f.message = "Searching…"
+		} else {
+			f.message = "No matches"
+		}
+		app.updateFindTitles()
+		return nil
+	}
+
+	line := s.lines[i]
+	f.largeLine = line
+	f.message = ""
+	app.currentLine = line
+	if app.currentLine > app.totalLines-app.viewportHeight {
+		app.currentLine = app.totalLines - app.viewportHeight
+	}
+	if app.currentLine < 0 {
+		app.currentLine = 0
+	}
+
+	content, err := app.getViewportContent()
+	if err != nil {
+		return err
+	}
+	app.currentContent = content
+	f.matches = findMatchesInLines(app.findTargetLines(), app.currentLine, f.pattern)
+	f.current = -1
+	for i, m := range f.matches {
+		if m.line == line {
+			f.current = i
+			break
+		}
+	}
+
+	app.updateMainView()
+	app.updateStatusBar()
+	app.updateFindTitles()
+	return nil
+}
@@ -0,0 +1,323 @@
+package main
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// =============================================================================
+// LINE OFFSET INDEX
+// =============================================================================
+
+// lineIndex records the byte offset of every Interval-th line of a file, so a
+// line can be reached with one seek and a short scan instead of a full read
+type lineIndex struct {
+	Path     string    `json:"path"`
+	Size     int64     `json:"size"`
+	ModTime  time.Time `json:"mtime"`
+	Lines    int       `json:"lines"`
+	Interval int       `json:"interval"`
+	Offsets  []int64   `json:"offsets"` // Offsets[k] is where line k*Interval starts
+}
+
+// defaultLineIndexDir returns where line indexes are kept between runs
+func defaultLineIndexDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "cui-notes", LINE_INDEX_DIR)
+	}
+	return filepath.Join(dir, "cui-notes", LINE_INDEX_DIR)
+}
+
+// current reports whether the index still describes the file
+func (idx *lineIndex) current(info os.FileInfo) bool {
+	return idx.Size == info.Size() && idx.ModTime.Equal(info.ModTime()) && idx.Interval == LINE_INDEX_INTERVAL
+}
+
+// seekLine returns the indexed line at or before line and its byte offset
+func (idx *lineIndex) seekLine(line int) (int, int64) {
+	k := line / idx.Interval
+	if k >= len(idx.Offsets) {
+		k = len(idx.Offsets) - 1
+	}
+	if k < 0 {
+		return 0, 0
+	}
+	return k * idx.Interval, idx.Offsets[k]
+}
+
+// errIndexCancelled is returned when indexing stops before reaching the end
+var errIndexCancelled = errors.New("line indexing cancelled")
+
+// buildLineIndex reads a file once, counting its lines and noting the offsets.
+// Lines are counted the way bufio.ScanLines splits them. progress, if set, is
+// called every INDEX_PROGRESS_MS; closing cancel stops the scan.
+func buildLineIndex(path string, info os.FileInfo, mapped bool, progress func(read int64, lines int), cancel <-chan struct{}) (*lineIndex, error) {
+	file, err := openLargeFile(path, mapped)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	idx := &lineIndex{Path: path, Interval: LINE_INDEX_INTERVAL}
+	if err := idx.extend(file, info, progress, cancel); err != nil {
+		return nil, err
+	}
+	return idx, nil
+}
+
+// extend indexes the bytes a file has gained past idx.Size, up to info.Size()
+func (idx *lineIndex) extend(file io.ReaderAt, info os.FileInfo, progress func(read int64, lines int), cancel <-chan struct{}) error {
+	offset := idx.Size
+	lines := idx.Lines
+	last := byte('\n')
+	if offset > 0 {
+		b := make([]byte, 1)
+		if _, err := file.ReadAt(b, offset-1); err != nil {
+			return err
+		}
+		last = b[0]
+	}
+	if last != '\n' {
+		lines-- // The unterminated last line is counted again once it ends
+	} else if lines%idx.Interval == 0 && len(idx.Offsets) == lines/idx.Interval {
+		idx.Offsets = append(idx.Offsets, offset) // Dropped while it was the end of the file
+	}
+
+	next := fileChunks(file, offset, info.Size())
+	lastProgress := time.Now()
+	for {
+		select {
+		case <-cancel:
+			return errIndexCancelled
+		default:
+		}
+		if progress != nil && time.Since(lastProgress) >= INDEX_PROGRESS_MS*time.Millisecond {
+			progress(offset, lines)
+			lastProgress = time.Now()
+		}
+
+		chunk, err := next()
+		for i, c := range chunk {
+			if c != '\n' {
+				continue
+			}
+			lines++
+			if lines%idx.Interval == 0 {
+				idx.Offsets = append(idx.Offsets, offset+int64(i)+1)
+			}
+		}
+		if n := len(chunk); n > 0 {
+			last = chunk[n-1]
+			offset += int64(n)
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	// An unterminated last line still counts
+	if offset > 0 && last != '\n' {
+		lines++
+	}
+	// Drop an offset pointing at the end of the file
+	if k := len(idx.Offsets); k > 0 && idx.Offsets[k-1] >= offset && offset > 0 {
+		idx.Offsets = idx.Offsets[:k-1]
+	}
+
+	idx.Size = offset
+	idx.ModTime = info.ModTime()
+	idx.Lines = lines
+	return nil
+}
+
+// lineIndexPath returns the on-disk location of a file's index
+func (app *App) lineIndexPath(path string) string {
+	sum := sha1.Sum([]byte(path))
+	return filepath.Join(app.lineIndexDir, hex.EncodeToString(sum[:8])+".json")
+}
+
+// indexPath returns the absolute path a file's index is keyed by
+func indexPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+// cachedLineIndex returns the index of a file from this run or from the cache
+// directory when the file's size and mtime are unchanged, or nil
+func (app *App) cachedLineIndex(path string, info os.FileInfo) *lineIndex {
+	if idx, ok := app.lineIndexes[path]; ok && idx.current(info) {
+		return idx
+	}
+
+	if app.lineIndexDir != "" {
+		if data, err := ioutil.ReadFile(app.lineIndexPath(path)); err == nil {
+			idx := &lineIndex{}
+			if json.Unmarshal(data, idx) == nil && idx.Path == path && idx.current(info) {
+				app.lineIndexes[path] = idx
+				return idx
+			}
+		}
+	}
+	return nil
+}
+
+// loadLineIndex returns the line index of a file, building it if no current
+// one is cached
+func (app *App) loadLineIndex(path string) (*lineIndex, error) {
+	path = indexPath(path)
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if idx := app.cachedLineIndex(path, info); idx != nil {
+		return idx, nil
+	}
+
+	idx, err := buildLineIndex(path, info, app.useMapping(), nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	app.lineIndexes[path] = idx
+	app.saveLineIndex(idx)
+	return idx, nil
+}
+
+// saveLineIndex writes an index to the cache directory; failures only cost a rebuild
+func (app *App) saveLineIndex(idx *lineIndex) {
+	if app.lineIndexDir == "" {
+		return
+	}
+	if err := os.MkdirAll(app.lineIndexDir, 0700); err != nil {
+		return
+	}
+	data, err := json.Marshal(idx)
+	if err != nil {
+		return
+	}
+
+	indexPath := app.lineIndexPath(idx.Path)
+	if err := ioutil.WriteFile(indexPath+".tmp", data, 0600); err != nil {
+		return
+	}
+	os.Rename(indexPath+".tmp", indexPath)
+}
+
+// =============================================================================
+// BACKGROUND INDEXING
+// =============================================================================
+
+// indexJob is a line index being built in the background
+type indexJob struct {
+	path   string
+	size   int64
+	read   int64 // bytes indexed so far
+	cancel chan struct{}
+}
+
+// startLineIndexing makes the index of a large file current. A cached index is
+// used at once; otherwise the file is indexed in the background while the
+// status bar shows progress, and the line count grows as lines are counted.
+func (app *App) startLineIndexing(filePath string) error {
+	app.cancelLineIndexing()
+
+	path := indexPath(filePath)
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	if idx := app.cachedLineIndex(path, info); idx != nil {
+		app.lineIndex = idx
+		app.totalLines = idx.Lines
+		return nil
+	}
+
+	job := &indexJob{path: path, size: info.Size(), cancel: make(chan struct{})}
+	app.indexJob = job
+	app.lineIndex = nil
+	app.totalLines = 0
+
+	mapped := app.useMapping()
+	go func() {
+		idx, err := buildLineIndex(path, info, mapped, func(read int64, lines int) {
+			app.events.post(indexProgressMsg{job, read, lines})
+		}, job.cancel)
+		if err == nil {
+			app.saveLineIndex(idx)
+		}
+		app.events.post(indexDoneMsg{job, idx, err})
+	}()
+	return nil
+}
+
+// indexProgressed shows how far indexing has got
+func (app *App) indexProgressed(m indexProgressMsg) {
+	if app.indexJob != m.job {
+		return
+	}
+	m.job.read = m.read
+	app.totalLines = m.lines
+	app.updateStatusBar()
+}
+
+// indexFinished makes a finished index current
+func (app *App) indexFinished(m indexDoneMsg) {
+	if app.indexJob != m.job {
+		return // Cancelled or replaced by another file
+	}
+	app.indexJob = nil
+	if m.err != nil {
+		app.updateStatusBar()
+		return
+	}
+	app.lineIndexes[m.job.path] = m.idx
+	app.lineIndex = m.idx
+	app.totalLines = m.idx.Lines
+	if s := app.follow; s != nil && s.auto {
+		// A followed file is shown from its end, also when reread after rotation
+		app.goToBottom()
+		s.shownLine = app.currentLine
+		return
+	}
+	app.updateMainView()
+	app.updateStatusBar()
+}
+
+// cancelLineIndexing stops indexing a file the user has moved away from
+func (app *App) cancelLineIndexing() {
+	if app.indexJob != nil {
+		close(app.indexJob.cancel)
+		app.indexJob = nil
+	}
+}
+
+// totalLinesLabel returns the line count of a large file, marked with "+"
+// while it is still being counted
+func (app *App) totalLinesLabel() string {
+	if app.indexJob != nil {
+		return fmt.Sprintf("%d+", app.totalLines)
+	}
+	return fmt.Sprintf("%d", app.totalLines)
+}
+
+// indexProgress returns the status bar note for indexing in progress
+func (app *App) indexProgress() string {
+	job := app.indexJob
+	if job == nil || job.size == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" | Indexing %d%%", job.read*100/job.size)
+}
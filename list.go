@@ -0,0 +1,368 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// =============================================================================
+// LIST EDITING
+// =============================================================================
+
+// listItemRegex matches a bullet, numbered, task or quote prefix
+var listItemRegex = regexp.MustCompile(`^(\s*)(?:([-*+])|(\d+)([.)])|(>+))(\s+|$)(\[[ xX]\]\s+)?`)
+
+// listItem is the markdown list prefix of a line
+type listItem struct {
+	indent string // leading whitespace
+	bullet string // "-", "*" or "+" for bullet items
+	number int    // number of an ordered item
+	digits string // number as written, e.g. "01"
+	delim  string // "." or ")" for ordered items
+	quote  string // ">" markers of a block quote
+	task   bool   // item starts with a [ ] checkbox
+	length int    // length of the whole prefix in runes
+}
+
+// parseListItem returns the list prefix of a line, if it has one
+func parseListItem(line string) (listItem, bool) {
+	m := listItemRegex.FindStringSubmatch(line)
+	if m == nil {
+		return listItem{}, false
+	}
+
+	item := listItem{
+		indent: m[1],
+		bullet: m[2],
+		delim:  m[4],
+		quote:  m[5],
+		task:   m[7] != "",
+		length: len([]rune(m[0])),
+	}
+	if m[3] != "" {
+		item.digits = m[3]
+		item.number, _ = strconv.Atoi(m[3])
+	}
+
+	// Bullets and numbers need a space after the marker, quotes don't
+	if item.quote == "" && m[6] == "" {
+		return listItem{}, false
+	}
+	return item, true
+}
+
+// ordered reports whether the item is a numbered list item
+func (item listItem) ordered() bool {
+	return item.delim != ""
+}
+
+// prefix returns the marker for the next item of the same list
+func (item listItem) prefix() string {
+	var marker string
+	switch {
+	case item.quote != "":
+		return item.indent + item.quote + " "
+	case item.ordered():
+		marker = item.formatNumber(item.number+1) + item.delim
+	default:
+		marker = item.bullet
+	}
+
+	if item.task {
+		return item.indent + marker + " [ ] "
+	}
+	return item.indent + marker + " "
+}
+
+// formatNumber writes number the way the item's number is written, keeping
+// zero padding such as "01"
+func (item listItem) formatNumber(number int) string {
+	if len(item.digits) > 1 && item.digits[0] == '0' {
+		return fmt.Sprintf("%0*d", len(item.digits), number)
+	}
+	return strconv.Itoa(number)
+}
+
+// indentWidth returns the indentation of a line in columns
+func indentWidth(line string) int {
+	width := 0
+	for _, r := range line {
+		switch r {
+		case ' ':
+			width++
+		case '\t':
+			width += LIST_INDENT_WIDTH
+		default:
+			return width
+		}
+	}
+	return width
+}
+
+// renumberList renumbers the ordered list containing line y, keeping its first number
+func renumberList(lines []string, y int) {
+	item, ok := parseListItem(lines[y])
+	if !ok || !item.ordered() {
+		return
+	}
+	level := indentWidth(lines[y])
+
+	// Walk back to the first item of the list at this level, skipping nested lines
+	start := y
+	for i := y - 1; i >= 0; i-- {
+		width := indentWidth(lines[i])
+		if strings.TrimSpace(lines[i]) == "" || width < level {
+			break
+		}
+		if width > level {
+			continue
+		}
+		prev, ok := parseListItem(lines[i])
+		if !ok || !prev.ordered() {
+			break
+		}
+		start = i
+	}
+
+	first, _ := parseListItem(lines[start])
+	number := first.number
+	for i := start; i < len(lines); i++ {
+		width := indentWidth(lines[i])
+		if strings.TrimSpace(lines[i]) == "" || width < level {
+			break
+		}
+		if width > level {
+			continue
+		}
+		current, ok := parseListItem(lines[i])
+		if !ok || !current.ordered() {
+			break
+		}
+		lines[i] = setListNumber(lines[i], current, number)
+		number++
+	}
+}
+
+// setListNumber replaces the number of an ordered list item
+func setListNumber(line string, item listItem, number int) string {
+	rest := line[len(item.indent)+len(item.digits):]
+	return item.indent + item.formatNumber(number) + rest
+}
+
+// continueList splits line y at x, carrying the list marker onto the new line.
+// It returns the updated lines and the new cursor position.
+func continueList(lines []string, x, y int) ([]string, int, int) {
+	line := []rune(lines[y])
+	if x > len(line) {
+		x = len(line)
+	}
+	before, after := string(line[:x]), string(line[x:])
+
+	item, ok := parseListItem(lines[y])
+	if ok && x >= item.length {
+		if strings.TrimSpace(string(line[item.length:])) == "" {
+			// Enter on an empty item ends the list; the items after it start a new one
+			lines[y] = ""
+			if y+1 < len(lines) && item.ordered() {
+				if next, ok := parseListItem(lines[y+1]); ok && next.ordered() && next.indent == item.indent {
+					lines[y+1] = setListNumber(lines[y+1], next, 1)
+				}
+				renumberList(lines, y+1)
+			}
+			return lines, 0, y
+		}
+
+		prefix := item.prefix()
+		lines = insertLine(lines, y+1, prefix+strings.TrimLeft(after, " "))
+		lines[y] = strings.TrimRight(before, " ")
+		if item.ordered() {
+			renumberList(lines, y)
+		}
+		return lines, len([]rune(prefix)), y + 1
+	}
+
+	// Not in a list: plain line split
+	lines[y] = before
+	lines = insertLine(lines, y+1, after)
+	return lines, 0, y + 1
+}
+
+// continueListInView runs continueList at the cursor and writes back only
+// the lines it changed. Lists end at blank lines, so only the block of lines
+// around the cursor is read, however long the note.
+func (app *App) continueListInView(v *gocui.View) {
+	x, y := v.Cursor()
+	if y >= v.LinesHeight() {
+		// Past the end of the buffer, which needs padding first
+		lines, x, y := editBufferLines(v)
+		lines, x, y = continueList(lines, x, y)
+		app.setEditLinesAt(v, lines, x, y)
+		return
+	}
+
+	first, end := y, y+1
+	for first > 0 && strings.TrimSpace(viewLine(v, first-1)) != "" {
+		first--
+	}
+	for end < v.LinesHeight() && strings.TrimSpace(viewLine(v, end)) != "" {
+		end++
+	}
+	block := make([]string, 0, end-first+1)
+	for i := first; i < end; i++ {
+		block = append(block, viewLine(v, i))
+	}
+
+	at := y - first
+	updated, cx, cy := continueList(append([]string(nil), block...), x, at)
+	if len(updated) > len(block) {
+		// Make room for the new line; it and the split line are rewritten below
+		v.EditNewLine()
+		block = insertLine(block, at+1, "")
+	}
+	for i, line := range updated {
+		if line != block[i] || i == at || i == at+1 {
+			v.SetLine(first+i, line)
+		}
+	}
+	v.SetCursor(cx, first+cy)
+	v.MoveCursor(0, 0) // Adjusts the origin so the cursor is on screen
+	app.schedulePreview()
+}
+
+// viewLine returns line y of the view's buffer as BufferLines does
+func viewLine(v *gocui.View, y int) string {
+	line, _ := v.Line(y)
+	return strings.Replace(line, "\x00", " ", -1)
+}
+
+// insertLine inserts a line at index i
+func insertLine(lines []string, i int, line string) []string {
+	lines = append(lines, "")
+	copy(lines[i+1:], lines[i:])
+	lines[i] = line
+	return lines
+}
+
+// editBufferLines returns the edit buffer lines and the cursor position within them
+func editBufferLines(v *gocui.View) ([]string, int, int) {
+	lines := v.BufferLines()
+	if len(lines) == 0 {
+		lines = []string{""}
+	}
+	x, y := v.Cursor()
+	if y >= len(lines) {
+		lines = append(lines, make([]string, y-len(lines)+1)...)
+	}
+	return lines, x, y
+}
+
+// setEditLinesAt replaces the edit buffer and moves the cursor to (x, y)
+func (app *App) setEditLinesAt(v *gocui.View, lines []string, x, y int) {
+	app.setEditLines(v, lines)
+	v.SetCursor(x, y)
+	v.MoveCursor(0, 0) // Adjusts the origin so the cursor is on screen
+}
+
+// handleIndent indents the current line (or list item) in edit mode;
+// outside edit mode the key toggles the sidebar as usual
+func (app *App) handleIndent(g *gocui.Gui, v *gocui.View) error {
+	if !app.isEditMode {
+		return app.toggleSidebar(g, v)
+	}
+	if app.completionShown() {
+		return app.acceptCompletion(v)
+	}
+	if app.snippet.active() {
+		app.nextSnippetStop(v)
+		return nil
+	}
+	return app.shiftLine(v, 1)
+}
+
+// handleOutdent outdents the current line (or list item) in edit mode
+func (app *App) handleOutdent(g *gocui.Gui, v *gocui.View) error {
+	if !app.isEditMode {
+		return nil
+	}
+	return app.shiftLine(v, -1)
+}
+
+// shiftLine indents (dir > 0) or outdents (dir < 0) the current line
+func (app *App) shiftLine(v *gocui.View, dir int) error {
+	if !v.Editable || (app.vimEnabled && app.vim.mode != vimInsert) {
+		return nil
+	}
+
+	lines, x, y := editBufferLines(v)
+	line := lines[y]
+	item, isList := parseListItem(line)
+
+	if dir > 0 && !isList {
+		// Plain lines just get an indent inserted at the cursor
+		runes := []rune(line)
+		if x > len(runes) {
+			x = len(runes)
+		}
+		lines[y] = string(runes[:x]) + LIST_INDENT + string(runes[x:])
+		app.setEditLinesAt(v, lines, x+LIST_INDENT_WIDTH, y)
+		return nil
+	}
+
+	oldLevel := indentWidth(line)
+	shift := LIST_INDENT_WIDTH
+	if dir > 0 {
+		lines[y] = LIST_INDENT + line
+	} else {
+		trimmed := strings.TrimLeft(line, " \t")
+		width := oldLevel - LIST_INDENT_WIDTH
+		if width < 0 {
+			width = 0
+		}
+		lines[y] = strings.Repeat(" ", width) + trimmed
+		shift = len([]rune(lines[y])) - len([]rune(line))
+	}
+
+	if isList && item.ordered() {
+		// A newly nested item starts its own list; the old level closes the gap
+		updated, _ := parseListItem(lines[y])
+		if dir > 0 && !previousSibling(lines, y) {
+			lines[y] = setListNumber(lines[y], updated, 1)
+		}
+		renumberList(lines, y)
+		for i := y + 1; i < len(lines); i++ {
+			if width := indentWidth(lines[i]); width <= oldLevel {
+				if width == oldLevel {
+					renumberList(lines, i)
+				}
+				break
+			}
+		}
+	}
+
+	x += shift
+	if x < 0 {
+		x = 0
+	}
+	app.setEditLinesAt(v, lines, x, y)
+	return nil
+}
+
+// previousSibling reports whether an ordered item precedes line y at the same level
+func previousSibling(lines []string, y int) bool {
+	level := indentWidth(lines[y])
+	for i := y - 1; i >= 0; i-- {
+		width := indentWidth(lines[i])
+		if strings.TrimSpace(lines[i]) == "" || width < level {
+			return false
+		}
+		if width == level {
+			item, ok := parseListItem(lines[i])
+			return ok && item.ordered()
+		}
+	}
+	return false
+}
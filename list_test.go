@@ -0,0 +1,65 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRenumberList(t *testing.T) {
+	tests := []struct {
+		lines, want []string
+	}{
+		{[]string{"1. a", "1. b", "5. c"}, []string{"1. a", "2. b", "3. c"}},
+		{[]string{"01. a", "01. b", "1. c"}, []string{"01. a", "02. b", "3. c"}},
+		{[]string{"9) a", "9) b", "  1. nested", "9) c"}, []string{"9) a", "10) b", "  1. nested", "11) c"}},
+		{[]string{"2. a", "20. b"}, []string{"2. a", "3. b"}},
+	}
+	for _, tt := range tests {
+		lines := append([]string(nil), tt.lines...)
+		renumberList(lines, 0)
+		if !reflect.DeepEqual(lines, tt.want) {
+			t.Errorf("renumber %q: got %q, want %q", tt.lines, lines, tt.want)
+		}
+	}
+}
+
+func TestEnterContinuesList(t *testing.T) {
+	note := "# Note\n\nintro\n\n01. first\n02. second\n03. third\n\n" + strings.Repeat("filler\n", 500)
+	app := newTestApp(t, map[string]string{"note.md": note})
+	selectFile(t, app, "note.md")
+	v, err := app.gui.View(MAIN_VIEW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.enterEditMode(app.gui, v); err != nil {
+		t.Fatal(err)
+	}
+
+	// Enter at the end of the first item inserts 02 and renumbers the rest
+	v.SetOrigin(0, 0)
+	v.SetCursor(len("01. first"), 4)
+	if err := app.handleEnterInMainView(app.gui, v); err != nil {
+		t.Fatal(err)
+	}
+	lines := v.BufferLines()
+	want := []string{"01. first", "02. ", "03. second", "04. third", ""}
+	if !reflect.DeepEqual(lines[4:9], want) {
+		t.Fatalf("list is %q, want %q", lines[4:9], want)
+	}
+	if x, y := v.Cursor(); x != len("02. ") || y != 5 {
+		t.Errorf("cursor at %d,%d, want %d,5", x, y, len("02. "))
+	}
+	if len(lines) != strings.Count(note, "\n")+2 || lines[9] != "filler" {
+		t.Errorf("buffer has %d lines after Enter", len(lines))
+	}
+
+	// Enter on the empty item ends the list; the items after it start again from 1
+	if err := app.handleEnterInMainView(app.gui, v); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"01. first", "", "01. second", "02. third", ""}
+	if lines := v.BufferLines(); !reflect.DeepEqual(lines[4:9], want) {
+		t.Fatalf("list is %q, want %q", lines[4:9], want)
+	}
+}
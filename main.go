@@ -1,19 +1,85 @@
 package main
 
 import (
+	"flag"
+	"fmt"
 	"log"
-	"time"
 
 	"github.com/awesome-gocui/gocui"
 )
 
 // main initializes and runs the application
 func main() {
+	vim := flag.Bool("vim", false, "enable Vim-style modal editing")
+	keymapPath := flag.String("keymap", defaultKeymapPath(), "path to the keymap file")
+	printKeys := flag.Bool("keys", false, "print the active key bindings and exit")
+	recoveryDir := flag.String("recovery-dir", defaultRecoveryDir(), "directory for swap files of unsaved edits")
+	preview := flag.Bool("preview", false, "show a live preview beside the editor on wide screens")
+	lineNumbers := flag.Bool("line-numbers", false, "show line numbers beside the main view")
+	spell := flag.Bool("spell", false, "underline misspelled words while editing")
+	dictDir := flag.String("dict-dir", DEFAULT_DICT_DIR, "directory with Hunspell .dic/.aff files")
+	dictLang := flag.String("dict", DEFAULT_DICT_LANG, "Hunspell dictionary name, e.g. en_GB")
+	clipboardMode := flag.String("clipboard", clipboardAuto, "clipboard backend: auto, system, osc52 or internal")
+	mmap := flag.Bool("mmap", true, "memory-map large files (Linux)")
+	autosave := flag.Duration("autosave", 0, "save edits after they have been idle this long (0 disables)")
+	flag.Parse()
+
 	app := NewApp()
+	app.vimEnabled = *vim
+	app.recoveryDir = *recoveryDir
+	app.autosaveIdle = *autosave
+	app.previewEnabled = *preview
+	app.lineNumbers = *lineNumbers
+	app.mapFiles = *mmap
+
+	switch *clipboardMode {
+	case clipboardAuto, clipboardSystem, clipboardOSC52, clipboardInternal:
+		app.clipboard.mode = *clipboardMode
+	default:
+		log.Fatalf("unknown clipboard backend %q", *clipboardMode)
+	}
+
+	if err := app.loadKeymap(*keymapPath); err != nil {
+		log.Fatalln(err)
+	}
+	if *printKeys {
+		fmt.Print(app.keymapHelp())
+		return
+	}
+
+	if *spell {
+		dict, err := loadHunspell(*dictDir, *dictLang)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		app.spell = dict
+		app.loadPersonalDict()
+	}
+
+	// Load existing items
+	app.loadItems()
+	app.pendingRecovery = app.loadRecoveryFiles()
+
+	for {
+		err := app.run()
+		if err == errExternalEdit {
+			// The GUI is closed while the external editor owns the terminal
+			app.runExternalEditor()
+			continue
+		}
+		if err != nil && err != gocui.ErrQuit {
+			log.Panicln(err)
+		}
+		return
+	}
+}
 
+// run creates the GUI and runs the main loop until the user quits
+// or the GUI is suspended for an external editor
+func (app *App) run() error {
 	g, err := gocui.NewGui(gocui.OutputNormal, true)
 	if err != nil {
-		log.Panicln(err)
+		return err
 	}
 	defer g.Close()
 
@@ -25,36 +91,52 @@ func main() {
 	g.SetManagerFunc(app.layout)
 
 	if err := app.setKeybindings(); err != nil {
-		log.Panicln(err)
+		return err
 	}
 
-	// Load existing items
-	app.loadItems()
+	// Keep swap files of unsaved edits current
+	stopRecovery := app.startRecoveryTimer()
+	defer close(stopRecovery)
+	defer app.stopFollow() // The poller posts to this GUI
 
-	// HACK: Force initial display by nudging the layout
-	go func() {
-		time.Sleep(100 * time.Millisecond) // Wait for initial layout
-
-		// Force a layout update to ensure content is displayed
-		app.forceLayout = true
-		app.viewsInitialized = false
-		g.Update(func(g *gocui.Gui) error {
-			return nil // This will trigger a layout update
-		})
-
-		// Set initial focus after layout is ready
-		time.Sleep(50 * time.Millisecond) // Brief additional delay
-		// Try to set focus to sidebar, fallback to any available view
-		if _, err := g.SetCurrentView(SIDEBAR_VIEW); err != nil {
-			// If sidebar doesn't exist, try main view
-			if _, err := g.SetCurrentView(MAIN_VIEW); err != nil {
-				// If neither exists, let gocui handle it
-				return
-			}
-		}
-	}()
+	// Views are recreated by the new GUI, so their content must be reloaded
+	app.viewsInitialized = false
+	focusView := SIDEBAR_VIEW
+	if app.resumeView != "" {
+		focusView = app.resumeView
+		app.resumeView = ""
+	}
 
-	if err := g.MainLoop(); err != nil && err != gocui.ErrQuit {
-		log.Panicln(err)
+	// Nudge the layout once the main loop is up, then focus and offer recovery
+	app.events.attach(g)
+	defer app.events.detach()
+	app.events.post(startupMsg{focus: focusView})
+
+	return g.MainLoop()
+}
+
+// startup runs on the main loop once the GUI is up: it forces a full layout
+// so content is displayed, focuses the requested view and offers recovery
+func (app *App) startup(focusView string) error {
+	app.forceLayout = true
+	app.viewsInitialized = false
+	err := app.layout(app.gui)
+	app.forceLayout = false
+	if err != nil {
+		return err
 	}
+
+	// Try to set focus to the requested view, fallback to the main view
+	if _, err := app.gui.SetCurrentView(focusView); err != nil {
+		if _, err := app.gui.SetCurrentView(MAIN_VIEW); err != nil {
+			return nil // If neither exists, let gocui handle it
+		}
+	}
+
+	// Notes may have changed while the GUI was closed
+	app.refreshVaultLists()
+
+	// Offer to recover edits left behind by an earlier session
+	app.offerRecovery()
+	return nil
 }
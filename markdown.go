@@ -1,6 +1,7 @@
 package main
 
 import (
+	"hash/maphash"
 	"regexp"
 	"strings"
 )
@@ -9,17 +10,18 @@ import (
 // MARKDOWN RENDERING
 // =============================================================================
 
-// renderMarkdown converts markdown content to display format
-func (app *App) renderMarkdown(content string) string {
-	lines := strings.Split(content, "\n")
-	var result []string
-
-	for _, line := range lines {
-		result = append(result, app.renderMarkdownLine(line))
-	}
-
-	return strings.Join(result, "\n")
-}
+// Inline markdown patterns, in the order they are applied
+var (
+	numberedItemRegex = regexp.MustCompile(`^(\d+)\. (.*)`)
+	superBoldRegex    = regexp.MustCompile(`\*\*\*([^*]+)\*\*\*`)
+	boldRegex         = regexp.MustCompile(`\*\*([^*]+)\*\*`)
+	italicRegex       = regexp.MustCompile(`\*([^*]+)\*`)
+	underlineRegex    = regexp.MustCompile(`__([^_]+)__`)
+	highlightRegex    = regexp.MustCompile(`==([^=]+)==`)
+	largeTextRegex    = regexp.MustCompile(`\^\^([^^]+)\^\^`)
+	codeSpanRegex     = regexp.MustCompile("`([^`]+)`")
+	strikeRegex       = regexp.MustCompile(`~~([^~]+)~~`)
+)
 
 // renderMarkdownLine processes a single line of markdown
 func (app *App) renderMarkdownLine(line string) string {
@@ -46,12 +48,8 @@ func (app *App) renderMarkdownLine(line string) string {
 	}
 
 	// Handle numbered lists (simple)
-	if matched, _ := regexp.MatchString(`^\d+\. `, line); matched {
-		re := regexp.MustCompile(`^(\d+)\. (.*)`)
-		matches := re.FindStringSubmatch(line)
-		if len(matches) >= 3 {
-			return "  " + matches[1] + ". " + matches[2]
-		}
+	if matches := numberedItemRegex.FindStringSubmatch(line); len(matches) >= 3 {
+		return "  " + matches[1] + ". " + matches[2]
 	}
 
 	// Handle inline markdown
@@ -63,28 +61,115 @@ func (app *App) renderMarkdownLine(line string) string {
 // renderInlineMarkdown processes inline markdown formatting
 func (app *App) renderInlineMarkdown(text string) string {
 	// Handle ***SUPER BOLD*** (triple asterisks) - keep as is for emphasis
-	text = regexp.MustCompile(`\*\*\*([^*]+)\*\*\*`).ReplaceAllString(text, "🔥$1🔥")
+	text = superBoldRegex.ReplaceAllString(text, "🔥$1🔥")
 
 	// Handle **bold** (double asterisks) - remove asterisks
-	text = regexp.MustCompile(`\*\*([^*]+)\*\*`).ReplaceAllString(text, "$1")
+	text = boldRegex.ReplaceAllString(text, "$1")
 
 	// Handle *italic* (single asterisks) - remove asterisks
-	text = regexp.MustCompile(`\*([^*]+)\*`).ReplaceAllString(text, "$1")
+	text = italicRegex.ReplaceAllString(text, "$1")
 
 	// Handle __underlined__ - remove underscores but keep content
-	text = regexp.MustCompile(`__([^_]+)__`).ReplaceAllString(text, "$1")
+	text = underlineRegex.ReplaceAllString(text, "$1")
 
 	// Handle ==highlighted== - remove equals but add visual indicator
-	text = regexp.MustCompile(`==([^=]+)==`).ReplaceAllString(text, "✨$1✨")
+	text = highlightRegex.ReplaceAllString(text, "✨$1✨")
 
 	// Handle ^^large text^^ - remove carets but add visual indicator
-	text = regexp.MustCompile(`\^\^([^^]+)\^\^`).ReplaceAllString(text, "📢$1📢")
+	text = largeTextRegex.ReplaceAllString(text, "📢$1📢")
 
 	// Handle `code` - remove backticks but add visual indicator
-	text = regexp.MustCompile("`([^`]+)`").ReplaceAllString(text, "[$1]")
+	text = codeSpanRegex.ReplaceAllString(text, "[$1]")
 
 	// Handle ~~strikethrough~~ - remove tildes
-	text = regexp.MustCompile(`~~([^~]+)~~`).ReplaceAllString(text, "$1")
+	text = strikeRegex.ReplaceAllString(text, "$1")
 
 	return text
 }
+
+// =============================================================================
+// RENDER CACHE
+// =============================================================================
+
+// renderedDoc is a note rendered for display
+type renderedDoc struct {
+	sum   uint64 // hash of the source
+	size  int    // length of the source
+	text  string
+	lines []string
+	width int   // width rows was wrapped to (0: not wrapped)
+	rows  []int // wrapped row where each rendered line starts
+}
+
+// renderCache keeps the last rendered note and the blocks it was made of, so
+// showing the same note again costs a hash and an edit only re-renders the
+// blocks it touched
+type renderCache struct {
+	seed   maphash.Seed
+	doc    *renderedDoc
+	blocks map[uint64][]string // rendered lines of a block, by hash of its source
+}
+
+// renderDocument renders content, wrapping rows to width when it is positive.
+// Rendering is line for line, so rendered line i is source line i.
+func (app *App) renderDocument(content string, width int) *renderedDoc {
+	c := &app.render
+	if c.blocks == nil {
+		c.seed = maphash.MakeSeed()
+		c.blocks = make(map[uint64][]string)
+	}
+
+	sum := maphash.String(c.seed, content)
+	doc := c.doc
+	if doc == nil || doc.sum != sum || doc.size != len(content) {
+		doc = &renderedDoc{sum: sum, size: len(content), lines: app.renderBlocks(content)}
+		doc.text = strings.Join(doc.lines, "\n")
+		c.doc = doc
+	}
+
+	if width > 0 && doc.width != width {
+		doc.width = width
+		doc.rows = make([]int, len(doc.lines))
+		row := 0
+		for i, line := range doc.lines {
+			doc.rows[i] = row
+			row += len(wrapRows([]rune(line), width))
+		}
+	}
+	return doc
+}
+
+// renderBlocks renders content a block at a time, reusing blocks rendered
+// last time. A block is a run of lines up to and including a blank line.
+func (app *App) renderBlocks(content string) []string {
+	c := &app.render
+	used := make(map[uint64][]string)
+	var result []string
+
+	for last := false; !last; {
+		block := content
+		if end := strings.Index(content, "\n\n"); end >= 0 {
+			block = content[:end+1] // Up to the blank line, whose newline starts the next block
+			content = content[end+2:]
+		} else {
+			last = true
+		}
+
+		key := maphash.String(c.seed, block)
+		lines, ok := used[key]
+		if !ok {
+			lines, ok = c.blocks[key]
+		}
+		if !ok {
+			for _, line := range strings.Split(block, "\n") {
+				lines = append(lines, app.renderMarkdownLine(line))
+			}
+		}
+		used[key] = lines
+		result = append(result, lines...)
+	}
+
+	// Only the blocks of this note are kept
+	c.blocks = used
+	return result
+}
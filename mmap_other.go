@@ -0,0 +1,16 @@
+//go:build !linux
+
+package main
+
+import "errors"
+
+// openMappedFile is only supported on Linux; elsewhere files are read through seeks
+func openMappedFile(path string) (*mappedFile, error) {
+	return nil, errors.New("memory-mapped files are not supported on this platform")
+}
+
+// Close releases the file
+func (m *mappedFile) Close() error {
+	m.data = nil
+	return nil
+}
@@ -0,0 +1,222 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// =============================================================================
+// PICKER
+// =============================================================================
+
+// pickerItem is one entry of a picker list
+type pickerItem struct {
+	label string // text shown and matched against the filter
+	value string // value handed to the pick callback
+}
+
+// pickerState holds the filterable list overlay used to choose notes and the like
+type pickerState struct {
+	active     bool
+	title      string
+	items      []pickerItem
+	filtered   []pickerItem
+	selected   int
+	query      string
+	returnView string // view focused again when the picker closes
+
+	// onPick receives the chosen item, or nil if nothing matches the query
+	onPick func(item *pickerItem, query string) error
+}
+
+// openPicker shows a picker over the main view
+func (app *App) openPicker(title string, items []pickerItem, onPick func(item *pickerItem, query string) error) error {
+	returnView := MAIN_VIEW
+	if current := app.gui.CurrentView(); current != nil {
+		returnView = current.Name()
+	}
+
+	app.picker = pickerState{
+		active:     true,
+		title:      title,
+		items:      items,
+		returnView: returnView,
+		onPick:     onPick,
+	}
+	app.filterPicker()
+
+	if err := app.layoutPicker(app.gui); err != nil {
+		return err
+	}
+	_, err := app.gui.SetCurrentView(PICKER_INPUT_VIEW)
+	return err
+}
+
+// layoutPicker places the filter field and list in the middle of the screen
+func (app *App) layoutPicker(g *gocui.Gui) error {
+	maxX, maxY := g.Size()
+
+	width := 60
+	if width > maxX-4 {
+		width = maxX - 4
+	}
+	height := PICKER_MAX_ROWS + 5
+	if height > maxY-4 {
+		height = maxY - 4
+	}
+	x0 := (maxX - width) / 2
+	y0 := (maxY - height) / 2
+
+	if v, err := g.SetView(PICKER_INPUT_VIEW, x0, y0, x0+width, y0+2, 0); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Title = app.picker.title
+		v.Editable = true
+		v.Editor = gocui.EditorFunc(app.pickerEdit)
+	}
+
+	if v, err := g.SetView(PICKER_VIEW, x0, y0+3, x0+width, y0+height, 0); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Highlight = true
+		v.SelBgColor = gocui.ColorGreen
+		v.SelFgColor = gocui.ColorBlack
+		v.Title = " " + app.formatKeyHints(", ",
+			keyHintSpec{PICKER_VIEW, []string{"picker_prev", "picker_next"}, "Move"},
+			keyHintSpec{PICKER_VIEW, []string{"picker_pick"}, "Pick"},
+			keyHintSpec{PICKER_VIEW, []string{"picker_close"}, "Cancel"}) + " "
+	}
+
+	app.updatePicker()
+	return nil
+}
+
+// updatePicker redraws the picker list with the selected entry highlighted
+func (app *App) updatePicker() {
+	v, err := app.gui.View(PICKER_VIEW)
+	if err != nil {
+		return
+	}
+
+	p := &app.picker
+	v.Clear()
+	for i, item := range p.filtered {
+		if i == p.selected {
+			fmt.Fprintf(v, "> %s\n", item.label)
+		} else {
+			fmt.Fprintf(v, "  %s\n", item.label)
+		}
+	}
+	if len(p.filtered) == 0 {
+		fmt.Fprint(v, "  (no matches)")
+	}
+
+	// Keep the selection on screen
+	_, height := v.Size()
+	_, oy := v.Origin()
+	if p.selected < oy {
+		oy = p.selected
+	} else if height > 0 && p.selected >= oy+height {
+		oy = p.selected - height + 1
+	}
+	v.SetOrigin(0, oy)
+	v.SetCursor(0, p.selected-oy)
+}
+
+// filterPickerItems keeps the items whose label contains every word of the query
+func filterPickerItems(items []pickerItem, query string) []pickerItem {
+	words := strings.Fields(strings.ToLower(query))
+
+	var filtered []pickerItem
+	for _, item := range items {
+		label := strings.ToLower(item.label)
+		matched := true
+		for _, word := range words {
+			if !strings.Contains(label, word) {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			filtered = append(filtered, item)
+		}
+	}
+	return filtered
+}
+
+// filterPicker applies the query to the picker list
+func (app *App) filterPicker() {
+	p := &app.picker
+	p.filtered = filterPickerItems(p.items, p.query)
+
+	if p.selected >= len(p.filtered) {
+		p.selected = len(p.filtered) - 1
+	}
+	if p.selected < 0 {
+		p.selected = 0
+	}
+}
+
+// pickerEdit is the editor for the filter field; it filters as you type
+func (app *App) pickerEdit(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	gocui.DefaultEditor.Edit(v, key, ch, mod)
+	app.picker.query = strings.TrimSpace(v.Buffer())
+	app.picker.selected = 0
+	app.filterPicker()
+	app.updatePicker()
+}
+
+// closePicker removes the picker and returns focus to where it was opened
+func (app *App) closePicker() {
+	app.picker.active = false
+	app.gui.DeleteView(PICKER_INPUT_VIEW)
+	app.gui.DeleteView(PICKER_VIEW)
+	app.gui.SetCurrentView(app.picker.returnView)
+}
+
+// =============================================================================
+// PICKER HANDLERS
+// =============================================================================
+
+// pickerNext selects the next entry
+func (app *App) pickerNext(g *gocui.Gui, v *gocui.View) error {
+	if app.picker.selected < len(app.picker.filtered)-1 {
+		app.picker.selected++
+		app.updatePicker()
+	}
+	return nil
+}
+
+// pickerPrev selects the previous entry
+func (app *App) pickerPrev(g *gocui.Gui, v *gocui.View) error {
+	if app.picker.selected > 0 {
+		app.picker.selected--
+		app.updatePicker()
+	}
+	return nil
+}
+
+// pickerPick closes the picker and hands the selection to its callback
+func (app *App) pickerPick(g *gocui.Gui, v *gocui.View) error {
+	p := app.picker
+	app.closePicker()
+
+	if p.onPick == nil {
+		return nil
+	}
+	if len(p.filtered) == 0 {
+		return p.onPick(nil, p.query)
+	}
+	item := p.filtered[p.selected]
+	return p.onPick(&item, p.query)
+}
+
+// pickerClose closes the picker without choosing anything
+func (app *App) pickerClose(g *gocui.Gui, v *gocui.View) error {
+	app.closePicker()
+	return nil
+}
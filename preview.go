@@ -0,0 +1,159 @@
+package main
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// =============================================================================
+// LIVE PREVIEW
+// =============================================================================
+
+// previewShown reports whether the preview fits beside the editor in a main area this wide
+func (app *App) previewShown(width int) bool {
+	return app.previewEnabled && app.isEditMode && !app.isLargeFile && width >= PREVIEW_MIN_WIDTH
+}
+
+// layoutMainArea places the main view, with the live preview beside it while editing
+func (app *App) layoutMainArea(g *gocui.Gui, x0, y0, x1, y1 int) error {
+	x0, err := app.layoutGutter(g, x0, y0, y1)
+	if err != nil {
+		return err
+	}
+
+	mainRight := x1
+	if app.previewShown(x1 - x0) {
+		mainRight = x0 + (x1-x0)/2
+		if v, err := g.SetView(PREVIEW_VIEW, mainRight+1, y0, x1, y1, 0); err != nil {
+			if err != gocui.ErrUnknownView {
+				return err
+			}
+			v.Title = " Preview "
+			v.Wrap = true
+			app.previewDirty = true
+		}
+	} else {
+		g.DeleteView(PREVIEW_VIEW)
+	}
+
+	if v, err := g.SetView(MAIN_VIEW, x0, y0, mainRight, y1, 0); err != nil {
+		if err != gocui.ErrUnknownView {
+			return err
+		}
+		v.Editor = app.mainEditor()
+		if app.isEditMode {
+			v.Title = " Edit Mode - " + app.editModeHints() + " "
+			v.Editable = true
+			v.Wrap = true
+		} else {
+			v.Title = " View Mode - " + app.viewModeHints() + " "
+			v.Editable = false
+			v.Wrap = true
+		}
+	}
+
+	if app.previewDirty {
+		app.updatePreview()
+	}
+	app.updateGutter()
+	return nil
+}
+
+// relayoutMainArea resizes the main area after the preview or gutter was shown or hidden
+func (app *App) relayoutMainArea(g *gocui.Gui) error {
+	maxX, maxY := g.Size()
+	if maxX < SMALL_SCREEN_WIDTH {
+		// Small screens have no room for a preview; only the gutter can change
+		if _, err := g.View(MAIN_VIEW); err != nil {
+			return nil
+		}
+		x0, err := app.layoutGutter(g, 0, 3, maxY-3)
+		if err != nil {
+			return err
+		}
+		_, err = g.SetView(MAIN_VIEW, x0, 3, maxX-1, maxY-3, 0)
+		return err
+	}
+	return app.layoutMainArea(g, app.sidebarWidth+1, 3, maxX-1, maxY-3)
+}
+
+// togglePreview shows or hides the live preview beside the editor
+func (app *App) togglePreview(g *gocui.Gui, v *gocui.View) error {
+	app.previewEnabled = !app.previewEnabled
+	app.previewDirty = true
+	return app.relayoutMainArea(g)
+}
+
+// schedulePreview refreshes the preview once typing pauses
+func (app *App) schedulePreview() {
+	if _, err := app.gui.View(PREVIEW_VIEW); err != nil {
+		return
+	}
+
+	if app.previewTimer != nil {
+		app.previewTimer.Stop()
+	}
+	app.previewTimer = time.AfterFunc(PREVIEW_DEBOUNCE_MS*time.Millisecond, func() {
+		app.events.post(previewMsg{})
+	})
+}
+
+// updatePreview renders the edit buffer into the preview
+func (app *App) updatePreview() {
+	app.previewDirty = false
+
+	v, err := app.gui.View(PREVIEW_VIEW)
+	if err != nil {
+		return
+	}
+	mainView, err := app.gui.View(MAIN_VIEW)
+	if err != nil {
+		return
+	}
+
+	// Remember where each rendered line starts once wrapped, for scroll syncing
+	width, _ := v.Size()
+	doc := app.renderDocument(mainView.Buffer(), width)
+	app.previewRows = doc.rows
+
+	v.Clear()
+	fmt.Fprint(v, doc.text)
+	app.previewSync = [3]int{-1, -1, -1}
+	app.syncPreviewScroll()
+}
+
+// syncPreviewScroll scrolls the preview so the cursor's line sits level with the cursor
+func (app *App) syncPreviewScroll() {
+	if len(app.previewRows) == 0 {
+		return
+	}
+	v, err := app.gui.View(PREVIEW_VIEW)
+	if err != nil {
+		return
+	}
+	mainView, err := app.gui.View(MAIN_VIEW)
+	if err != nil {
+		return
+	}
+
+	// Only recompute when the cursor or the editor's scroll position moved
+	cx, cy := mainView.Cursor()
+	_, oy := mainView.Origin()
+	if app.previewSync == [3]int{cx, cy, oy} {
+		return
+	}
+	app.previewSync = [3]int{cx, cy, oy}
+
+	line := cy
+	if line >= len(app.previewRows) {
+		line = len(app.previewRows) - 1
+	}
+	_, screenRow := cursorScreenPos(mainView)
+	origin := app.previewRows[line] - screenRow
+	if origin < 0 {
+		origin = 0
+	}
+	v.SetOrigin(0, origin)
+}
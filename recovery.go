@@ -0,0 +1,587 @@
+package main
+
+import (
+	"bufio"
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// =============================================================================
+// CRASH RECOVERY
+// =============================================================================
+
+// recoveryFile is a swap file holding the unsaved edit buffer of a note
+type recoveryFile struct {
+	Path    string    `json:"path"`            // absolute path of the note
+	Content string    `json:"content"`         // edit buffer at the time of writing
+	Large   bool      `json:"large,omitempty"` // Content is a large file's edits (largeSwap)
+	Saved   time.Time `json:"saved"`
+
+	swapPath string // location of the swap file itself
+}
+
+// defaultRecoveryDir returns the swap file directory in the user cache directory
+func defaultRecoveryDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return filepath.Join(os.TempDir(), "cui-notes", RECOVERY_DIR)
+	}
+	return filepath.Join(dir, "cui-notes", RECOVERY_DIR)
+}
+
+// swapPathFor returns the swap file path for a note
+func (app *App) swapPathFor(notePath string) string {
+	sum := sha1.Sum([]byte(notePath))
+	return filepath.Join(app.recoveryDir, hex.EncodeToString(sum[:8])+SWAP_EXT)
+}
+
+// editingNotePath returns the absolute path of the note being edited
+func (app *App) editingNotePath() string {
+	if app.editPath == "" {
+		return ""
+	}
+	notePath, err := filepath.Abs(filepath.Join(app.notesDir, app.editPath))
+	if err != nil {
+		return ""
+	}
+	return notePath
+}
+
+// startEditRecovery starts tracking the edit buffer of the current note
+func (app *App) startEditRecovery(path string) {
+	app.editPath = path
+	app.swapContent = app.currentContent
+	app.lastBufferChange = time.Now()
+}
+
+// writeSwap writes the edit buffer to the note's swap file
+func (app *App) writeSwap(content string) error {
+	notePath := app.editingNotePath()
+	if notePath == "" || app.recoveryDir == "" {
+		return nil
+	}
+
+	if err := os.MkdirAll(app.recoveryDir, 0700); err != nil {
+		return err
+	}
+
+	rec := recoveryFile{Path: notePath, Content: content, Large: app.largeEdit != nil, Saved: time.Now()}
+	data, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	// Write then rename so a crash never leaves a half-written swap file
+	swapPath := app.swapPathFor(notePath)
+	if err := ioutil.WriteFile(swapPath+".tmp", data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(swapPath+".tmp", swapPath)
+}
+
+// removeSwap deletes the swap file of the note being edited
+func (app *App) removeSwap() {
+	if notePath := app.editingNotePath(); notePath != "" && app.recoveryDir != "" {
+		os.Remove(app.swapPathFor(notePath))
+	}
+}
+
+// flushSwap writes the edit buffer to the swap file if it has unsaved changes
+func (app *App) flushSwap() {
+	if !app.isEditMode {
+		return
+	}
+
+	v, err := app.gui.View(MAIN_VIEW)
+	if err != nil {
+		return
+	}
+
+	if content, modified := app.swapBuffer(v); modified {
+		app.writeSwap(content)
+	}
+}
+
+// swapBuffer returns the edit buffer as its swap file holds it, and whether
+// it has unsaved changes. A large file is swapped as its edits, not its text.
+func (app *App) swapBuffer(v *gocui.View) (string, bool) {
+	if app.largeEdit != nil {
+		return app.largeSwapContent(v), app.largeEditModified(v)
+	}
+	content := v.Buffer()
+	return content, content != app.originalContent
+}
+
+// startRecoveryTimer periodically checks the edit buffer from the main loop.
+// Closing the returned channel stops the timer.
+func (app *App) startRecoveryTimer() chan struct{} {
+	stop := make(chan struct{})
+
+	go func() {
+		ticker := time.NewTicker(SWAP_INTERVAL_MS * time.Millisecond)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				app.events.post(recoveryTickMsg{})
+			}
+		}
+	}()
+
+	return stop
+}
+
+// recoveryTick refreshes the swap file and runs the idle autosave
+func (app *App) recoveryTick(g *gocui.Gui) error {
+	if !app.isEditMode || app.editPath == "" {
+		return nil
+	}
+
+	v, err := g.View(MAIN_VIEW)
+	if err != nil {
+		return nil
+	}
+
+	content, modified := app.swapBuffer(v)
+	if content != app.swapContent {
+		// The buffer changed since the last tick: keep the swap file current
+		app.swapContent = content
+		app.lastBufferChange = time.Now()
+		if !modified {
+			app.removeSwap()
+		} else {
+			app.writeSwap(content)
+		}
+		return nil
+	}
+
+	// Autosave once the buffer has been idle long enough
+	if app.autosaveIdle > 0 && modified &&
+		time.Since(app.lastBufferChange) >= app.autosaveIdle {
+		return app.saveNote(g, v)
+	}
+
+	return nil
+}
+
+// loadRecoveryFiles reads the swap files left behind by earlier sessions
+func (app *App) loadRecoveryFiles() []recoveryFile {
+	files, err := ioutil.ReadDir(app.recoveryDir)
+	if err != nil {
+		return nil
+	}
+
+	var recoveries []recoveryFile
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), SWAP_EXT) {
+			continue
+		}
+
+		swapPath := filepath.Join(app.recoveryDir, file.Name())
+		data, err := ioutil.ReadFile(swapPath)
+		if err != nil {
+			continue
+		}
+
+		var rec recoveryFile
+		if err := json.Unmarshal(data, &rec); err != nil || rec.Path == "" {
+			continue
+		}
+		rec.swapPath = swapPath
+		recoveries = append(recoveries, rec)
+	}
+
+	return recoveries
+}
+
+// offerRecovery asks what to do with the next leftover swap file
+func (app *App) offerRecovery() {
+	if len(app.pendingRecovery) == 0 || app.showingDialog {
+		return
+	}
+
+	rec := app.pendingRecovery[0]
+	name := rec.Path
+	if notesDir, err := filepath.Abs(app.notesDir); err == nil {
+		if rel, err := filepath.Rel(notesDir, rec.Path); err == nil && !strings.HasPrefix(rel, "..") {
+			name = rel
+		}
+	}
+
+	prompt := fmt.Sprintf("%s (%s)\n r: Recover, d: Show diff, x: Discard", name, rec.Saved.Format("Jan 2 15:04"))
+	if rec.Large && !rec.applies() {
+		// A large file's edits are line numbers into the note as it was
+		prompt = fmt.Sprintf("%s (%s)\n The note changed since, so its edits no longer apply.\n x: Discard, anything else: Keep", name, rec.Saved.Format("Jan 2 15:04"))
+	}
+	app.showDialog("recover", " Recover Unsaved Changes ", prompt, func(response string) error {
+		switch strings.ToLower(strings.TrimSpace(response)) {
+		case "r", "recover":
+			if rec.Large && !rec.applies() {
+				break
+			}
+			if err := app.recoverNote(rec); err != nil {
+				return err
+			}
+		case "d", "diff":
+			app.showRecoveryDiff(rec)
+			app.offerRecovery()
+			return nil
+		case "x", "discard":
+			os.Remove(rec.swapPath)
+		}
+		// Anything else keeps the swap file for the next start
+		return app.nextRecovery()
+	})
+}
+
+// nextRecovery moves on to the next swap file, refreshing the UI after the last one
+func (app *App) nextRecovery() error {
+	app.pendingRecovery = app.pendingRecovery[1:]
+	if len(app.pendingRecovery) > 0 {
+		app.offerRecovery()
+		return nil
+	}
+	return app.refreshItems(app.gui, nil)
+}
+
+// recoverNote writes a recovered buffer back to its note and removes the swap file
+func (app *App) recoverNote(rec recoveryFile) error {
+	if rec.Large {
+		if err := rec.recoverLarge(); err != nil {
+			return err
+		}
+		if head, err := app.readNoteHead(rec.Path); err == nil {
+			app.updateNoteTitle(filepath.Base(rec.Path), head)
+		}
+		os.Remove(rec.swapPath)
+		return nil
+	}
+
+	if err := os.MkdirAll(filepath.Dir(rec.Path), 0755); err != nil {
+		return err
+	}
+	if err := app.saveNoteContent(rec.Path, rec.Content); err != nil {
+		return err
+	}
+	app.updateNoteTitle(filepath.Base(rec.Path), rec.Content)
+	os.Remove(rec.swapPath)
+	return nil
+}
+
+// showRecoveryDiff shows the differences between a note on disk and its recovered buffer
+func (app *App) showRecoveryDiff(rec recoveryFile) {
+	v, err := app.gui.View(MAIN_VIEW)
+	if err != nil {
+		return
+	}
+
+	v.Title = fmt.Sprintf(" Recovery Diff - %s (-: on disk, +: recovered) ", filepath.Base(rec.Path))
+	v.Clear()
+	if rec.Large {
+		// Only the edited lines, as the note may not fit in memory
+		diff, err := rec.largeDiff()
+		if err != nil {
+			fmt.Fprint(v, "Cannot compare: "+err.Error())
+			return
+		}
+		fmt.Fprint(v, strings.Join(diff, "\n"))
+		return
+	}
+
+	onDisk := ""
+	if content, err := ioutil.ReadFile(rec.Path); err == nil {
+		onDisk = string(content)
+	}
+
+	diff := diffLines(strings.Split(onDisk, "\n"), strings.Split(rec.Content, "\n"))
+	if len(diff) == 0 {
+		fmt.Fprint(v, "No differences")
+		return
+	}
+	fmt.Fprint(v, strings.Join(diff, "\n"))
+}
+
+// =============================================================================
+// LARGE FILE RECOVERY
+// =============================================================================
+
+// largeSwap is the swap file content of a large file: its piece table over the
+// note as it was on disk, rather than the whole document
+type largeSwap struct {
+	Size     int64       `json:"size"` // size and time of the note the edits apply to
+	ModTime  time.Time   `json:"mod_time"`
+	Newline  string      `json:"newline"`
+	Trailing bool        `json:"trailing"`
+	Pieces   []swapPiece `json:"pieces"`
+}
+
+// swapPiece is a run of lines of the note, or edited lines when Lines is set
+type swapPiece struct {
+	Start int      `json:"start,omitempty"`
+	Count int      `json:"count,omitempty"`
+	Lines []string `json:"lines,omitempty"`
+}
+
+// largeSwapContent returns the large file's edits, including the window still
+// in the main view, as the content of its swap file
+func (app *App) largeSwapContent(v *gocui.View) string {
+	le := app.largeEdit
+	info, err := os.Stat(app.editingNotePath())
+	if err != nil {
+		return ""
+	}
+
+	table := le.table.clone()
+	if lines := v.BufferLines(); strings.Join(lines, "\n") != le.winText {
+		table.replace(le.winStart, le.winLen, lines)
+	}
+
+	swap := largeSwap{Size: info.Size(), ModTime: info.ModTime(), Newline: le.newline, Trailing: le.trailing}
+	for _, p := range table.pieces {
+		if p.added {
+			swap.Pieces = append(swap.Pieces, swapPiece{Lines: table.added[p.start : p.start+p.count]})
+		} else {
+			swap.Pieces = append(swap.Pieces, swapPiece{Start: p.start, Count: p.count})
+		}
+	}
+	data, err := json.Marshal(swap)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// largeSwap decodes the edits of a large file's swap file
+func (rec recoveryFile) largeSwap() (*largeSwap, error) {
+	var swap largeSwap
+	if err := json.Unmarshal([]byte(rec.Content), &swap); err != nil {
+		return nil, err
+	}
+	return &swap, nil
+}
+
+// applies reports whether a large file's edits still fit the note on disk
+func (rec recoveryFile) applies() bool {
+	swap, err := rec.largeSwap()
+	if err != nil {
+		return false
+	}
+	info, err := os.Stat(rec.Path)
+	return err == nil && info.Size() == swap.Size && info.ModTime().Equal(swap.ModTime)
+}
+
+// recoverLarge writes a large file's edits onto the note they were made on
+func (rec recoveryFile) recoverLarge() error {
+	if !rec.applies() {
+		return fmt.Errorf("%s changed since its edits were saved", filepath.Base(rec.Path))
+	}
+	swap, err := rec.largeSwap()
+	if err != nil {
+		return err
+	}
+
+	table := &pieceTable{}
+	for _, p := range swap.Pieces {
+		if len(p.Lines) > 0 {
+			table.pieces = append(table.pieces, linePiece{added: true, start: len(table.added), count: len(p.Lines)})
+			table.added = append(table.added, p.Lines...)
+		} else {
+			table.pieces = append(table.pieces, linePiece{start: p.Start, count: p.Count})
+		}
+		table.total += table.pieces[len(table.pieces)-1].count
+	}
+	return writePieceTable(rec.Path, table, swap.Newline, swap.Trailing)
+}
+
+// largeDiff diffs the runs of lines a large file's edits replace, streaming
+// the note rather than loading it
+func (rec recoveryFile) largeDiff() ([]string, error) {
+	swap, err := rec.largeSwap()
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(rec.Path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	reader := bufio.NewReader(file)
+
+	// Each hunk is the note's lines up to the next file piece against the
+	// edited lines in their place
+	var out, removed, added []string
+	fileLine, docLine := 0, 0
+	flush := func() {
+		if len(removed) > 0 || len(added) > 0 {
+			first := 0
+			for first < len(removed) && first < len(added) && removed[first] == added[first] {
+				first++
+			}
+			out = append(out, fmt.Sprintf("@@ line %d", docLine-len(added)+first+1))
+			out = append(out, diffLines(removed, added)...)
+		}
+		removed, added = nil, nil
+	}
+	readUpTo := func(end int, keep bool) error {
+		for ; end < 0 || fileLine < end; fileLine++ {
+			line, err := readFileLine(reader)
+			if err == io.EOF && end < 0 {
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+			if keep {
+				removed = append(removed, line)
+			}
+		}
+		return nil
+	}
+
+	for _, p := range swap.Pieces {
+		if len(p.Lines) > 0 {
+			added = append(added, p.Lines...)
+			docLine += len(p.Lines)
+			continue
+		}
+		if err := readUpTo(p.Start, true); err != nil {
+			return nil, err
+		}
+		flush()
+		if err := readUpTo(p.Start+p.Count, false); err != nil {
+			return nil, err
+		}
+		docLine += p.Count
+	}
+	if err := readUpTo(-1, true); err != nil { // Lines cut from the end
+		return nil, err
+	}
+	flush()
+
+	if len(out) == 0 {
+		return []string{"No differences"}, nil
+	}
+	return out, nil
+}
+
+// diffLines returns a line diff of a and b with a little context around each change
+func diffLines(a, b []string) []string {
+	// Trim the common prefix and suffix so only the changed middle is compared
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	midA := a[prefix : len(a)-suffix]
+	midB := b[prefix : len(b)-suffix]
+
+	// ops holds one entry per line: ' ' kept, '-' removed, '+' added
+	type op struct {
+		kind byte
+		text string
+	}
+	var ops []op
+	for _, line := range a[:prefix] {
+		ops = append(ops, op{' ', line})
+	}
+
+	if len(midA)*len(midB) <= DIFF_MAX_CELLS {
+		// Longest common subsequence over the changed lines
+		lcs := make([][]int, len(midA)+1)
+		for i := range lcs {
+			lcs[i] = make([]int, len(midB)+1)
+		}
+		for i := len(midA) - 1; i >= 0; i-- {
+			for j := len(midB) - 1; j >= 0; j-- {
+				if midA[i] == midB[j] {
+					lcs[i][j] = lcs[i+1][j+1] + 1
+				} else if lcs[i+1][j] >= lcs[i][j+1] {
+					lcs[i][j] = lcs[i+1][j]
+				} else {
+					lcs[i][j] = lcs[i][j+1]
+				}
+			}
+		}
+
+		i, j := 0, 0
+		for i < len(midA) || j < len(midB) {
+			switch {
+			case i < len(midA) && j < len(midB) && midA[i] == midB[j]:
+				ops = append(ops, op{' ', midA[i]})
+				i++
+				j++
+			case j < len(midB) && (i == len(midA) || lcs[i][j+1] > lcs[i+1][j]):
+				ops = append(ops, op{'+', midB[j]})
+				j++
+			default:
+				ops = append(ops, op{'-', midA[i]})
+				i++
+			}
+		}
+	} else {
+		// Too large to compare line by line: show the middle as replaced
+		for _, line := range midA {
+			ops = append(ops, op{'-', line})
+		}
+		for _, line := range midB {
+			ops = append(ops, op{'+', line})
+		}
+	}
+
+	for _, line := range a[len(a)-suffix:] {
+		ops = append(ops, op{' ', line})
+	}
+
+	// Keep changed lines plus DIFF_CONTEXT lines around them
+	keep := make([]bool, len(ops))
+	for i, o := range ops {
+		if o.kind == ' ' {
+			continue
+		}
+		for k := i - DIFF_CONTEXT; k <= i+DIFF_CONTEXT; k++ {
+			if k >= 0 && k < len(ops) {
+				keep[k] = true
+			}
+		}
+	}
+
+	var out []string
+	skipped := false
+	for i, o := range ops {
+		if !keep[i] {
+			skipped = true
+			continue
+		}
+		if skipped && len(out) > 0 {
+			out = append(out, "  ...")
+		}
+		skipped = false
+
+		switch o.kind {
+		case '-':
+			out = append(out, DIFF_DELETE_STYLE+"- "+o.text+ANSI_RESET)
+		case '+':
+			out = append(out, DIFF_INSERT_STYLE+"+ "+o.text+ANSI_RESET)
+		default:
+			out = append(out, "  "+o.text)
+		}
+	}
+
+	return out
+}
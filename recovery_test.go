@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLargeFileRecovery(t *testing.T) {
+	const lines = 30000
+	app := newTestApp(t, map[string]string{"big.log": numberedLines(0, lines)})
+	selectFile(t, app, "big.log")
+	drainUntil(t, app, func() bool { return app.indexJob == nil })
+
+	v, err := app.gui.View(MAIN_VIEW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.enterEditMode(app.gui, v); err != nil || app.largeEdit == nil {
+		t.Fatalf("large edit mode not entered: %v", err)
+	}
+
+	// Edit the first line, then a line in a later window left unfolded
+	v.SetCursor(0, 0)
+	v.EditWrite('>')
+	if err := app.moveEditWindow(v, 20000, 0); err != nil {
+		t.Fatal(err)
+	}
+	_, cy := v.Cursor()
+	window := v.BufferLines()
+	v.Clear()
+	fmt.Fprint(v, strings.Join(append(window[:cy], window[cy+1:]...), "\n"))
+	app.flushSwap()
+
+	recs := app.loadRecoveryFiles()
+	if len(recs) != 1 || !recs[0].Large {
+		t.Fatalf("got %d swap files, want one for the large file", len(recs))
+	}
+	rec := recs[0]
+	if size := len(numberedLines(0, lines)); len(rec.Content) > size/4 {
+		t.Errorf("swap file holds %d of the note's %d bytes, want only the edited windows", len(rec.Content), size)
+	}
+
+	diff, err := rec.largeDiff()
+	if err != nil {
+		t.Fatal(err)
+	}
+	text := strings.Join(diff, "\n")
+	if len(diff) > 4*DIFF_CONTEXT+6 {
+		t.Errorf("diff has %d lines, want only the changes in context", len(diff))
+	}
+	for _, want := range []string{"@@ line 1", "- line 0 of", "+ >line 0 of", "@@ line 20001", "- line 20000 of"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("diff lacks %q:\n%s", want, text)
+		}
+	}
+
+	// The edits are written onto the note left behind
+	if err := app.recoverNote(rec); err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(app.notesDir, "big.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := ">" + numberedLines(0, 20000) + numberedLines(20001, lines-20001)
+	if string(data) != want {
+		t.Fatalf("recovered %d bytes, want %d", len(data), len(want))
+	}
+	if _, err := os.Stat(rec.swapPath); !os.IsNotExist(err) {
+		t.Error("swap file left after recovering")
+	}
+
+	// Edits made on an older version of the note no longer apply
+	app.flushSwap()
+	rec = app.loadRecoveryFiles()[0]
+	if !rec.applies() {
+		t.Fatal("fresh edits do not apply")
+	}
+	if err := os.WriteFile(rec.Path, []byte("rewritten\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if rec.applies() || rec.recoverLarge() == nil {
+		t.Error("edits applied to a note that changed since")
+	}
+}
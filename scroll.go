@@ -3,7 +3,11 @@ package main
 import (
 	"bufio"
 	"fmt"
+	"io"
 	"strings"
+	"unicode/utf8"
+
+	"github.com/awesome-gocui/gocui"
 )
 
 // =============================================================================
@@ -44,7 +48,23 @@ func (app *App) getViewportContent() (string, error) {
 		return "", nil
 	}
 
-	viewportLines := app.lineCache[startIdx:endIdx]
+	// Long lines are cut at the view's width unless the current one is wrapped
+	width := 0
+	if v, err := app.gui.View(MAIN_VIEW); err == nil {
+		width, _ = v.Size()
+	}
+	viewportLines := make([]string, 0, endIdx-startIdx)
+	for i := startIdx; i < endIdx; i++ {
+		viewportLines = append(viewportLines, app.clipLine(app.lineCache[i], app.lineCut[i], width))
+	}
+	if app.wrapCurrentLine && startIdx == app.currentLine-app.cacheStartLine && len(viewportLines) > 0 {
+		if line, more, err := app.readLongLine(app.currentLine); err == nil {
+			if more {
+				line += LINE_CUT_MARKER
+			}
+			viewportLines[0] = line
+		}
+	}
 	return strings.Join(viewportLines, "\n"), nil
 }
 
@@ -57,12 +77,13 @@ func (app *App) ensureLinesInCache(startLine, endLine int) error {
 	if cacheStart < 0 {
 		cacheStart = 0
 	}
-	if cacheEnd > app.totalLines {
-		cacheEnd = app.totalLines
+	if cacheEnd > app.totalLines && app.indexJob == nil {
+		cacheEnd = app.totalLines // While indexing, reading stops at the end of the file
 	}
 
 	// Check if we already have these lines cached
-	if app.cacheStartLine <= cacheStart && app.cacheEndLine >= cacheEnd && app.lineCache != nil {
+	if app.cacheStartLine <= cacheStart && app.cacheEndLine >= cacheEnd && app.lineCache != nil &&
+		app.cacheColumn == app.segmentColumn() {
 		return nil // Already cached
 	}
 
@@ -76,29 +97,215 @@ func (app *App) loadLinesIntoCache(startLine, endLine int) error {
 		return fmt.Errorf("file not open")
 	}
 
-	// Reset file position
-	app.fileHandle.Seek(0, 0)
+	lines, cut, err := app.readLineSegments(startLine, endLine)
+	app.lineCache = lines
+	app.lineCut = cut
+	app.cacheStartLine = startLine
+	app.cacheEndLine = startLine + len(lines)
+	app.cacheColumn = app.segmentColumn()
+	return err
+}
 
-	scanner := bufio.NewScanner(app.fileHandle)
-	app.lineCache = make([]string, 0, endLine-startLine)
+// readLineSegments reads lines [startLine, endLine) of the open file, keeping
+// only the segment of each line around the horizontal scroll
+func (app *App) readLineSegments(startLine, endLine int) ([]string, []bool, error) {
+	// Start from the nearest indexed line rather than the top of the file
+	currentLineNum, offset := 0, int64(0)
+	if app.lineIndex != nil {
+		currentLineNum, offset = app.lineIndex.seekLine(startLine)
+	}
+	reader := app.fileHandle.lines(offset)
+	lines := make([]string, 0, endLine-startLine)
+	cut := make([]bool, 0, endLine-startLine)
 
-	currentLineNum := 0
+	from, max := app.segmentColumn(), LINE_SEGMENT_BYTES
+	if from < 0 {
+		from, max = 0, -1
+	}
 
 	// Skip lines before our range
-	for currentLineNum < startLine && scanner.Scan() {
+	for currentLineNum < startLine {
+		if _, _, err := reader.segment(0, 0); err == io.EOF {
+			return lines, cut, nil
+		} else if err != nil {
+			return lines, cut, err
+		}
 		currentLineNum++
 	}
 
 	// Read lines in our range
-	for currentLineNum < endLine && scanner.Scan() {
-		app.lineCache = append(app.lineCache, scanner.Text())
+	for currentLineNum < endLine {
+		segment, more, err := reader.segment(from, max)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return lines, cut, err
+		}
+		lines = append(lines, segment)
+		cut = append(cut, more)
 		currentLineNum++
 	}
+	return lines, cut, nil
+}
 
-	app.cacheStartLine = startLine
-	app.cacheEndLine = currentLineNum
+// =============================================================================
+// LONG LINES
+// =============================================================================
+
+// readLineSegment reads one line of any length, returning at most max bytes of
+// it from byte from on (max < 0 keeps the rest of the line) and whether the
+// line goes on past them. The line ending is dropped.
+func readLineSegment(r *bufio.Reader, from, max int) (string, bool, error) {
+	var segment []byte
+	length := 0
+	read := false
+	var last byte
+
+	for {
+		chunk, err := r.ReadSlice('\n')
+		if len(chunk) > 0 {
+			read = true
+		}
+		if err == nil {
+			chunk = chunk[:len(chunk)-1]
+		}
+
+		// Keep the part of the chunk that falls in [from, from+max)
+		lo, hi := from-length, len(chunk)
+		if lo < 0 {
+			lo = 0
+		}
+		if max >= 0 && from+max-length < hi {
+			hi = from + max - length
+		}
+		if lo < hi {
+			segment = append(segment, chunk[lo:hi]...)
+		}
+		if len(chunk) > 0 {
+			last = chunk[len(chunk)-1]
+		}
+		length += len(chunk)
+
+		if err == bufio.ErrBufferFull {
+			continue
+		}
+		if err != nil && err != io.EOF {
+			return "", false, err
+		}
+		if err == io.EOF && !read {
+			return "", false, io.EOF
+		}
+		if err == nil && last == '\r' {
+			length-- // CRLF line ending
+		}
+		break
+	}
+
+	if keep := length - from; keep < len(segment) {
+		if keep < 0 {
+			keep = 0
+		}
+		segment = segment[:keep]
+	}
+	more := max >= 0 && from+max < length
+	return string(segment), more, nil
+}
 
-	return scanner.Err()
+// segmentColumn returns the byte at which cached line segments start for the
+// current horizontal scroll, or -1 when whole lines are needed for editing
+func (app *App) segmentColumn() int {
+	if app.largeEdit != nil {
+		return -1
+	}
+	return app.hscroll - app.hscroll%(LINE_SEGMENT_BYTES/2)
+}
+
+// clipLine returns the part of a cached line segment shown at the current
+// horizontal scroll, with markers where the line goes on past the view
+func (app *App) clipLine(segment string, more bool, width int) string {
+	rel := app.hscroll - app.cacheColumn
+	if app.cacheColumn < 0 {
+		rel = app.hscroll
+	}
+	if rel >= len(segment) {
+		if app.hscroll > 0 && (rel > 0 || more) {
+			return LINE_CUT_LEFT_MARKER
+		}
+		return ""
+	}
+
+	// Start on a whole character
+	for rel > 0 && rel < len(segment) && !utf8.RuneStart(segment[rel]) {
+		rel++
+	}
+	runes := []rune(strings.ToValidUTF8(segment[rel:], ""))
+
+	prefix := ""
+	if app.hscroll > 0 {
+		prefix = LINE_CUT_LEFT_MARKER
+		width--
+	}
+	if width > 0 && (len(runes) > width || (more && len(runes) == width)) {
+		return prefix + string(runes[:width-1]) + LINE_CUT_MARKER
+	}
+	if more {
+		return prefix + string(runes) + LINE_CUT_MARKER
+	}
+	return prefix + string(runes)
+}
+
+// readLongLine reads up to LONG_LINE_WRAP_BYTES of a line from the horizontal
+// scroll position, for showing it wrapped
+func (app *App) readLongLine(line int) (string, bool, error) {
+	lineNum, offset := 0, int64(0)
+	if app.lineIndex != nil {
+		lineNum, offset = app.lineIndex.seekLine(line)
+	}
+	reader := app.fileHandle.lines(offset)
+	for ; lineNum < line; lineNum++ {
+		if _, _, err := reader.segment(0, 0); err != nil {
+			return "", false, err
+		}
+	}
+	segment, more, err := reader.segment(app.hscroll, LONG_LINE_WRAP_BYTES)
+	return strings.ToValidUTF8(segment, ""), more, err
+}
+
+// scrollHorizontal moves the large-file viewer half a view left or right
+func (app *App) scrollHorizontal(v *gocui.View, dir int) error {
+	width, _ := v.Size()
+	step := width / 2
+	if step < 1 {
+		step = 1
+	}
+
+	app.hscroll += dir * step
+	if app.hscroll < 0 {
+		app.hscroll = 0
+	}
+	return app.refreshViewport()
+}
+
+// toggleWrapLine shows the line at the top of the large-file viewer in full
+func (app *App) toggleWrapLine(g *gocui.Gui, v *gocui.View) error {
+	if !app.isLargeFile || app.isEditMode {
+		return nil
+	}
+	app.wrapCurrentLine = !app.wrapCurrentLine
+	return app.refreshViewport()
+}
+
+// refreshViewport reloads the large-file viewport at the current position
+func (app *App) refreshViewport() error {
+	content, err := app.getViewportContent()
+	if err != nil {
+		return err
+	}
+	app.currentContent = content
+	app.updateMainView()
+	app.updateStatusBar()
+	return nil
 }
 
 // =============================================================================
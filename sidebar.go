@@ -0,0 +1,199 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// =============================================================================
+// SIDEBAR LIST
+// =============================================================================
+
+// sidebarState is the scroll position and type-ahead of the file list. Only
+// the rows in view are drawn, so folders with many files stay responsive.
+type sidebarState struct {
+	top    int    // first item shown
+	rows   int    // rows the list was last drawn for
+	typing bool   // letters go to the type-ahead
+	typed  string // letters typed to jump to an item
+}
+
+// updateSidebar draws the items in view and puts the cursor on the selected one
+func (app *App) updateSidebar() {
+	v, err := app.gui.View(SIDEBAR_VIEW)
+	if err != nil {
+		return
+	}
+
+	_, rows := v.Size()
+	if rows < 1 {
+		rows = 1
+	}
+	s := &app.sidebar
+	s.rows = rows
+
+	// Scroll just enough to keep the selection in view
+	if app.currentItem < s.top {
+		s.top = app.currentItem
+	}
+	if app.currentItem >= s.top+rows {
+		s.top = app.currentItem - rows + 1
+	}
+	if s.top > len(app.items)-rows {
+		s.top = len(app.items) - rows
+	}
+	if s.top < 0 {
+		s.top = 0
+	}
+
+	v.Clear()
+	v.SetOrigin(0, 0)
+	end := s.top + rows
+	if end > len(app.items) {
+		end = len(app.items)
+	}
+	for i := s.top; i < end; i++ {
+		if i == app.currentItem {
+			fmt.Fprintf(v, "> %s\n", app.items[i].Title)
+		} else {
+			fmt.Fprintf(v, "  %s\n", app.items[i].Title)
+		}
+	}
+	v.SetCursor(0, app.currentItem-s.top)
+}
+
+// syncSidebarRows redraws the list when the sidebar changed height
+func (app *App) syncSidebarRows() {
+	if v, err := app.gui.View(SIDEBAR_VIEW); err == nil {
+		if _, rows := v.Size(); rows != app.sidebar.rows {
+			app.updateSidebar()
+		}
+	}
+}
+
+// selectSidebarItem selects item i, clamped to the list, and shows it
+func (app *App) selectSidebarItem(i int) {
+	if i >= len(app.items) {
+		i = len(app.items) - 1
+	}
+	if i < 0 {
+		i = 0
+	}
+	if i == app.currentItem {
+		return
+	}
+	app.cancelLineIndexing()
+	app.statusNote = ""
+	app.currentItem = i
+	app.loadCurrentItem()
+	app.updateSidebar()
+	app.updateHeader()
+}
+
+// sidebarPageUp selects the item a page above the selection
+func (app *App) sidebarPageUp(g *gocui.Gui, v *gocui.View) error {
+	app.selectSidebarItem(app.currentItem - app.sidebar.rows)
+	return nil
+}
+
+// sidebarPageDown selects the item a page below the selection
+func (app *App) sidebarPageDown(g *gocui.Gui, v *gocui.View) error {
+	app.selectSidebarItem(app.currentItem + app.sidebar.rows)
+	return nil
+}
+
+// sidebarTop selects the first item
+func (app *App) sidebarTop(g *gocui.Gui, v *gocui.View) error {
+	app.selectSidebarItem(0)
+	return nil
+}
+
+// sidebarBottom selects the last item
+func (app *App) sidebarBottom(g *gocui.Gui, v *gocui.View) error {
+	app.selectSidebarItem(len(app.items) - 1)
+	return nil
+}
+
+// =============================================================================
+// TYPE-AHEAD
+// =============================================================================
+
+// sidebarEditor feeds typing in the sidebar to the type-ahead. The sidebar is
+// editable only so keys reach this editor; the list itself is never edited.
+// Outside type-ahead, letters run the actions bound to them (d, r, /, ...).
+func (app *App) sidebarEditor() gocui.Editor {
+	return gocui.EditorFunc(func(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+		s := &app.sidebar
+		if key == gocui.KeySpace {
+			ch = ' '
+		}
+		if !s.typing {
+			if ch != 0 && !app.runRuneBinding(SIDEBAR_VIEW, v, ch, mod) {
+				app.runRuneBinding(GLOBAL_SCOPE, v, ch, mod)
+			}
+			return
+		}
+
+		switch {
+		case key == gocui.KeyBackspace || key == gocui.KeyBackspace2:
+			if typed := []rune(s.typed); len(typed) > 0 {
+				app.typeAhead(string(typed[:len(typed)-1]))
+				return
+			}
+			app.endTypeAhead()
+		case key == gocui.KeyEsc:
+			app.endTypeAhead()
+		case ch != 0 && mod == gocui.ModNone && unicode.IsPrint(ch):
+			app.typeAhead(s.typed + string(ch))
+		case ch != 0:
+			// Modified keys end the type-ahead and run their action
+			app.endTypeAhead()
+			if !app.runRuneBinding(SIDEBAR_VIEW, v, ch, mod) {
+				app.runRuneBinding(GLOBAL_SCOPE, v, ch, mod)
+			}
+		}
+	})
+}
+
+// startTypeAhead starts jumping to items by typing the start of their name
+func (app *App) startTypeAhead(g *gocui.Gui, v *gocui.View) error {
+	app.sidebar.typing = true
+	app.sidebar.typed = ""
+	app.updateStatusBar()
+	return nil
+}
+
+// endTypeAhead stops the type-ahead, leaving the item it reached selected
+func (app *App) endTypeAhead() {
+	if app.sidebar.typing {
+		app.sidebar.typing = false
+		app.sidebar.typed = ""
+		app.updateStatusBar()
+	}
+}
+
+// typeAhead selects the first item whose name starts with typed, ignoring case
+func (app *App) typeAhead(typed string) {
+	s := &app.sidebar
+	s.typed = typed
+
+	prefix := strings.ToLower(typed)
+	for i, item := range app.items {
+		if typed == "" {
+			break // Nothing to jump to yet
+		}
+		// Titles start with an icon and a space
+		title := item.Title
+		if sp := strings.IndexByte(title, ' '); sp >= 0 {
+			title = title[sp+1:]
+		}
+		if strings.HasPrefix(strings.ToLower(title), prefix) || strings.HasPrefix(strings.ToLower(item.Name), prefix) {
+			app.selectSidebarItem(i)
+			break
+		}
+	}
+	app.updateStatusBar()
+}
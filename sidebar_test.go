@@ -0,0 +1,47 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// typeInSidebar sends keys to the sidebar's editor as gocui would
+func typeInSidebar(app *App, keys ...interface{}) {
+	v, _ := app.gui.View(SIDEBAR_VIEW)
+	for _, k := range keys {
+		switch k := k.(type) {
+		case rune:
+			v.Editor.Edit(v, 0, k, gocui.ModNone)
+		case gocui.Key:
+			v.Editor.Edit(v, k, 0, gocui.ModNone)
+		}
+	}
+}
+
+func TestSidebarTypeAhead(t *testing.T) {
+	app := newTestApp(t, map[string]string{
+		"apple.md":  "# Apple\n",
+		"dates.md":  "# Dates\n",
+		"recipe.md": "# Recipes\n",
+		"rules.md":  "# Rules\n",
+	})
+	selected := func() string { return app.items[app.currentItem].Name }
+
+	// Letters bound to actions are typed, not run, once type-ahead started
+	typeInSidebar(app, '/', 'r', 'u')
+	if selected() != "rules.md" || app.showingDialog {
+		t.Fatalf("selected %s (dialog %v), want rules.md", selected(), app.showingDialog)
+	}
+	typeInSidebar(app, gocui.KeyBackspace2, 'e')
+	if selected() != "recipe.md" {
+		t.Fatalf("selected %s after backspace, want recipe.md", selected())
+	}
+	typeInSidebar(app, gocui.KeyEsc, 'd')
+	if !app.showingDialog || app.dialogType != "confirm_delete" {
+		t.Fatalf("d after Esc did not ask to delete (dialog %q)", app.dialogType)
+	}
+	if selected() != "recipe.md" {
+		t.Fatalf("selection moved to %s", selected())
+	}
+}
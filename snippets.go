@@ -0,0 +1,299 @@
+package main
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// =============================================================================
+// SNIPPETS
+// =============================================================================
+
+// snippet is a trigger such as ";meet" and the text it expands to
+type snippet struct {
+	trigger string
+	body    string
+}
+
+// snippetStop is a tab stop of an expanded snippet. It is kept relative to the
+// end of the buffer and of its line, so typing at earlier stops doesn't move it.
+type snippetStop struct {
+	fromBottom int // lines between the stop and the last line
+	fromEnd    int // runes between the stop and the end of its line
+}
+
+// snippetSession tracks the tab stops left to visit after an expansion
+type snippetSession struct {
+	stops []snippetStop
+	next  int
+}
+
+// active reports whether there are tab stops left to jump to
+func (s *snippetSession) active() bool {
+	return s.next < len(s.stops)
+}
+
+// snippetVarRegex matches {{name}} variables and {{1}} / {{1:default}} tab stops
+var snippetVarRegex = regexp.MustCompile(`\{\{(\w+)(?::([^}]*))?\}\}`)
+
+// loadSnippets reads the snippets file in the vault. Each "## <trigger>" heading
+// starts a snippet; its body is the first fenced block below it, or else the
+// lines up to the next heading.
+func (app *App) loadSnippets() []snippet {
+	content, err := ioutil.ReadFile(filepath.Join(app.notesDir, SNIPPETS_FILE))
+	if err != nil {
+		return nil
+	}
+
+	var snippets []snippet
+	var current *snippet
+	var body []string
+	fenced, inFence := false, false
+
+	flush := func() {
+		if current != nil {
+			current.body = strings.TrimRight(strings.Join(body, "\n"), "\n ")
+			if !fenced {
+				current.body = strings.TrimLeft(current.body, "\n")
+			}
+			snippets = append(snippets, *current)
+		}
+		current, body, fenced, inFence = nil, nil, false, false
+	}
+
+	for _, line := range strings.Split(string(content), "\n") {
+		line = strings.TrimRight(line, "\r")
+		fence := strings.HasPrefix(strings.TrimSpace(line), "```")
+		switch {
+		case inFence && fence:
+			inFence = false
+		case inFence:
+			body = append(body, line)
+		case current != nil && fence && !fenced && strings.TrimSpace(strings.Join(body, "")) == "":
+			// The first fenced block is the whole body
+			body, fenced, inFence = nil, true, true
+		case strings.HasPrefix(line, "## "):
+			flush()
+			if trigger := strings.TrimSpace(strings.TrimPrefix(line, "## ")); trigger != "" {
+				current = &snippet{trigger: trigger}
+			}
+		case current != nil && !fenced:
+			body = append(body, line)
+		}
+	}
+	flush()
+
+	// Longest triggers first, so ";todo2" wins over ";todo"
+	sort.SliceStable(snippets, func(i, j int) bool {
+		return len(snippets[i].trigger) > len(snippets[j].trigger)
+	})
+	return snippets
+}
+
+// expandSnippetBody fills in the variables of a snippet body. It returns the
+// text and the rune offsets of its tab stops (in order) and of {{cursor}}.
+func (app *App) expandSnippetBody(body string) (string, []int, int) {
+	now := time.Now()
+	title, folder := "", ""
+	if len(app.items) > 0 {
+		item := app.items[app.currentItem]
+		title = app.noteTitles[item.Name]
+		if title == "" {
+			title = strings.TrimSuffix(item.Name, filepath.Ext(item.Name))
+		}
+		if dir := filepath.Dir(item.Path); dir != "." {
+			folder = filepath.Base(dir)
+		}
+	}
+
+	var b strings.Builder
+	runes := 0
+	cursor := -1
+	numbered := make(map[int]int) // stop number -> offset
+	last := 0
+	write := func(s string) {
+		b.WriteString(s)
+		runes += len([]rune(s))
+	}
+
+	for _, m := range snippetVarRegex.FindAllStringSubmatchIndex(body, -1) {
+		write(body[last:m[0]])
+		last = m[1]
+		name := body[m[2]:m[3]]
+		def := ""
+		if m[4] >= 0 {
+			def = body[m[4]:m[5]]
+		}
+
+		switch name {
+		case "date":
+			write(now.Format(SNIPPET_DATE_FORMAT))
+		case "time":
+			write(now.Format(SNIPPET_TIME_FORMAT))
+		case "title":
+			write(title)
+		case "folder":
+			write(folder)
+		case "cursor":
+			cursor = runes
+		default:
+			n, err := strconv.Atoi(name)
+			if err != nil {
+				write(body[m[0]:m[1]]) // Unknown variables are left as typed
+				continue
+			}
+			write(def)
+			if _, seen := numbered[n]; !seen {
+				numbered[n] = runes // The cursor lands after the default text
+			}
+		}
+	}
+	write(body[last:])
+
+	order := make([]int, 0, len(numbered))
+	for n := range numbered {
+		order = append(order, n)
+	}
+	sort.Ints(order)
+	stops := make([]int, len(order))
+	for i, n := range order {
+		stops[i] = numbered[n]
+	}
+	return b.String(), stops, cursor
+}
+
+// =============================================================================
+// SNIPPET EXPANSION
+// =============================================================================
+
+// expandSnippet replaces a trigger just typed before the cursor with its snippet.
+// A trigger that begins a longer trigger expands once a space follows it.
+func (app *App) expandSnippet(v *gocui.View) bool {
+	if len(app.snippets) == 0 || (app.vimEnabled && app.vim.mode != vimInsert) {
+		return false
+	}
+
+	lines, x, y := editBufferLines(v)
+	line := []rune(lines[y])
+	if x > len(line) {
+		x = len(line)
+	}
+	before := string(line[:x])
+
+	for _, s := range app.snippets {
+		typed := s.trigger
+		if app.triggerIsPrefix(s.trigger) {
+			typed += " "
+		}
+		if !strings.HasSuffix(before, typed) {
+			continue
+		}
+		start := x - len([]rune(typed))
+		if start > 0 && line[start-1] != ' ' && line[start-1] != '\t' {
+			continue // Triggers only count at the start of a word
+		}
+
+		suffix := strings.TrimPrefix(typed, s.trigger)
+		app.insertSnippet(v, lines, y, start, x, s.body, suffix)
+		return true
+	}
+	return false
+}
+
+// triggerIsPrefix reports whether another trigger starts with this one
+func (app *App) triggerIsPrefix(trigger string) bool {
+	for _, s := range app.snippets {
+		if s.trigger != trigger && strings.HasPrefix(s.trigger, trigger) {
+			return true
+		}
+	}
+	return false
+}
+
+// insertSnippet replaces runes [start, end) of line y with a snippet body
+func (app *App) insertSnippet(v *gocui.View, lines []string, y, start, end int, body, suffix string) {
+	line := []rune(lines[y])
+	var indent []rune
+	for _, r := range line {
+		if r != ' ' && r != '\t' {
+			break
+		}
+		indent = append(indent, r)
+	}
+
+	// The space that ended the trigger stays, unless the snippet places the cursor
+	text, stops, cursor := app.expandSnippetBody(body)
+	if cursor < 0 {
+		text += suffix
+		cursor = len([]rune(text))
+	}
+
+	// Continuation lines take the indentation of the trigger's line
+	expanded := strings.Split(text, "\n")
+	for i := 1; i < len(expanded); i++ {
+		expanded[i] = string(indent) + expanded[i]
+	}
+	before, after := string(line[:start]), string(line[end:])
+	expanded[0] = before + expanded[0]
+	expanded[len(expanded)-1] += after
+
+	newLines := append([]string{}, lines[:y]...)
+	newLines = append(newLines, expanded...)
+	newLines = append(newLines, lines[y+1:]...)
+
+	// Map offsets in the expanded text to buffer positions
+	position := func(offset int) (int, int) {
+		row, col := y, start
+		for _, r := range []rune(text)[:offset] {
+			if r == '\n' {
+				row++
+				col = len(indent)
+			} else {
+				col++
+			}
+		}
+		return col, row
+	}
+
+	app.snippet = snippetSession{}
+	for _, offset := range append(stops, cursor) {
+		col, row := position(offset)
+		app.snippet.stops = append(app.snippet.stops, snippetStop{
+			fromBottom: len(newLines) - 1 - row,
+			fromEnd:    len([]rune(newLines[row])) - col,
+		})
+	}
+
+	app.setEditLines(v, newLines)
+	app.nextSnippetStop(v)
+}
+
+// nextSnippetStop moves the cursor to the next tab stop of the expanded snippet
+func (app *App) nextSnippetStop(v *gocui.View) {
+	s := &app.snippet
+	if !s.active() {
+		return
+	}
+	stop := s.stops[s.next]
+	s.next++
+
+	lines := v.BufferLines()
+	row := len(lines) - 1 - stop.fromBottom
+	if row < 0 || row >= len(lines) {
+		app.snippet = snippetSession{}
+		return
+	}
+	col := len([]rune(lines[row])) - stop.fromEnd
+	if col < 0 {
+		col = 0
+	}
+	v.SetCursor(col, row)
+	keepCursorVisible(v)
+}
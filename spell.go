@@ -0,0 +1,622 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// =============================================================================
+// HUNSPELL DICTIONARIES
+// =============================================================================
+
+// condPart is one character position of an affix condition: ".", "x", "[xy]" or "[^xy]"
+type condPart struct {
+	any    bool
+	negate bool
+	chars  string
+}
+
+// affixRule is one PFX or SFX line of a .aff file
+type affixRule struct {
+	flag  string // flag the stem must carry
+	cross bool   // combines with affixes of the other kind
+	strip string // removed from the stem before adding
+	add   string // added to the stem
+	cond  []condPart
+}
+
+// hunspellDict checks words against a Hunspell .dic/.aff pair. Words are checked
+// by stripping affixes back to a dictionary stem rather than expanding every stem.
+type hunspellDict struct {
+	words    map[string][]string     // stem -> flags of each entry
+	prefixes map[string][]*affixRule // by the text the rule adds
+	suffixes map[string][]*affixRule
+	flagMode string // "", "long", "num" or "UTF-8"
+	try      string // characters tried when suggesting
+	rep      [][2]string
+	personal map[string]bool // words added by the user
+	checked  map[string]bool // results of earlier checks
+}
+
+// loadHunspell reads <dir>/<lang>.aff and <dir>/<lang>.dic
+func loadHunspell(dir, lang string) (*hunspellDict, error) {
+	d := &hunspellDict{
+		words:    make(map[string][]string),
+		prefixes: make(map[string][]*affixRule),
+		suffixes: make(map[string][]*affixRule),
+		personal: make(map[string]bool),
+		checked:  make(map[string]bool),
+	}
+
+	aff, err := ioutil.ReadFile(filepath.Join(dir, lang+".aff"))
+	if err != nil {
+		return nil, err
+	}
+	dic, err := ioutil.ReadFile(filepath.Join(dir, lang+".dic"))
+	if err != nil {
+		return nil, err
+	}
+
+	latin1 := !utf8.Valid(aff) // SET ISO8859-x and friends
+	d.parseAff(decodeDictText(aff, latin1))
+	d.parseDic(decodeDictText(dic, latin1))
+	return d, nil
+}
+
+// decodeDictText returns dictionary text as UTF-8, reading 8-bit text as Latin-1
+func decodeDictText(data []byte, latin1 bool) string {
+	if !latin1 {
+		return string(data)
+	}
+	runes := make([]rune, len(data))
+	for i, b := range data {
+		runes[i] = rune(b)
+	}
+	return string(runes)
+}
+
+// parseAff reads the affix rules and settings used for checking and suggesting
+func (d *hunspellDict) parseAff(text string) {
+	headers := make(map[string]bool) // "PFX A" -> cross product allowed
+
+	for _, line := range strings.Split(text, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) < 2 || strings.HasPrefix(fields[0], "#") {
+			continue
+		}
+
+		switch fields[0] {
+		case "FLAG":
+			d.flagMode = fields[1]
+		case "TRY":
+			d.try = fields[1]
+		case "REP":
+			if len(fields) >= 3 {
+				d.rep = append(d.rep, [2]string{
+					strings.Replace(fields[1], "_", " ", -1),
+					strings.Replace(fields[2], "_", " ", -1),
+				})
+			}
+		case "PFX", "SFX":
+			key := fields[0] + " " + fields[1]
+			if len(fields) == 4 {
+				if _, err := strconv.Atoi(fields[3]); err == nil {
+					if _, seen := headers[key]; !seen {
+						headers[key] = fields[2] == "Y"
+						continue
+					}
+				}
+			}
+			if len(fields) < 4 {
+				continue
+			}
+
+			rule := &affixRule{flag: fields[1], cross: headers[key]}
+			if fields[2] != "0" {
+				rule.strip = fields[2]
+			}
+			add := fields[3]
+			if i := strings.Index(add, "/"); i >= 0 {
+				add = add[:i] // Continuation flags are not supported
+			}
+			if add != "0" {
+				rule.add = add
+			}
+			if len(fields) >= 5 {
+				rule.cond = parseCondition(fields[4])
+			}
+
+			if fields[0] == "PFX" {
+				d.prefixes[rule.add] = append(d.prefixes[rule.add], rule)
+			} else {
+				d.suffixes[rule.add] = append(d.suffixes[rule.add], rule)
+			}
+		}
+	}
+}
+
+// parseDic reads the word list; its first line is the entry count
+func (d *hunspellDict) parseDic(text string) {
+	lines := strings.Split(text, "\n")
+	for _, line := range lines[1:] {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if i := strings.IndexAny(line, " \t"); i >= 0 {
+			line = line[:i] // Drop morphological fields
+		}
+
+		word, flags := line, ""
+		if i := strings.Index(line, "/"); i > 0 {
+			word, flags = line[:i], line[i+1:]
+		}
+		entry := d.words[word]
+		if entry == nil {
+			entry = []string{}
+		}
+		d.words[word] = append(entry, d.splitFlags(flags)...)
+	}
+}
+
+// splitFlags splits a flag string according to the FLAG setting
+func (d *hunspellDict) splitFlags(flags string) []string {
+	var out []string
+	switch d.flagMode {
+	case "long":
+		runes := []rune(flags)
+		for i := 0; i+1 < len(runes); i += 2 {
+			out = append(out, string(runes[i:i+2]))
+		}
+	case "num":
+		for _, f := range strings.Split(flags, ",") {
+			if f != "" {
+				out = append(out, f)
+			}
+		}
+	default:
+		for _, r := range flags {
+			out = append(out, string(r))
+		}
+	}
+	return out
+}
+
+// parseCondition parses an affix condition such as "[^aeiou]y"
+func parseCondition(cond string) []condPart {
+	if cond == "." {
+		return nil
+	}
+	var parts []condPart
+	runes := []rune(cond)
+	for i := 0; i < len(runes); i++ {
+		switch runes[i] {
+		case '.':
+			parts = append(parts, condPart{any: true})
+		case '[':
+			j := i + 1
+			part := condPart{}
+			if j < len(runes) && runes[j] == '^' {
+				part.negate = true
+				j++
+			}
+			start := j
+			for j < len(runes) && runes[j] != ']' {
+				j++
+			}
+			part.chars = string(runes[start:j])
+			parts = append(parts, part)
+			i = j
+		default:
+			parts = append(parts, condPart{chars: string(runes[i])})
+		}
+	}
+	return parts
+}
+
+// matchCondition checks a condition against the start (prefix) or end (suffix) of a stem
+func matchCondition(cond []condPart, stem string, atEnd bool) bool {
+	runes := []rune(stem)
+	if len(cond) > len(runes) {
+		return false
+	}
+	if atEnd {
+		runes = runes[len(runes)-len(cond):]
+	}
+	for i, part := range cond {
+		if part.any {
+			continue
+		}
+		if strings.ContainsRune(part.chars, runes[i]) == part.negate {
+			return false
+		}
+	}
+	return true
+}
+
+// hasFlag reports whether some entry of stem carries flag
+func (d *hunspellDict) hasFlag(stem, flag string) bool {
+	for _, f := range d.words[stem] {
+		if f == flag {
+			return true
+		}
+	}
+	return false
+}
+
+// check reports whether a word is spelled correctly
+func (d *hunspellDict) check(word string) bool {
+	if ok, seen := d.checked[word]; seen {
+		return ok
+	}
+	ok := false
+	for _, form := range caseForms(word) {
+		if d.personal[form] || d.lookup(form) {
+			ok = true
+			break
+		}
+	}
+	d.checked[word] = ok
+	return ok
+}
+
+// caseForms returns the spellings a word may be listed under
+func caseForms(word string) []string {
+	forms := []string{word}
+	lower := strings.ToLower(word)
+	r, size := utf8.DecodeRuneInString(word)
+	title := unicode.IsUpper(r) && strings.ToLower(word[size:]) == word[size:]
+	if lower == word || (!title && strings.ToUpper(word) != word) {
+		return forms // Mixed case like "iPhone" must match exactly
+	}
+	forms = append(forms, lower)
+	if strings.ToUpper(word) == word {
+		// ALL CAPS may stand for a capitalized word
+		r, size := utf8.DecodeRuneInString(lower)
+		forms = append(forms, string(unicode.ToUpper(r))+lower[size:])
+	}
+	return forms
+}
+
+// lookup finds word as a stem or a stem with one prefix and/or suffix
+func (d *hunspellDict) lookup(word string) bool {
+	if _, ok := d.words[word]; ok {
+		return true
+	}
+
+	// Suffixes, each optionally combined with a prefix
+	for i := range word {
+		for _, rule := range d.suffixes[word[i:]] {
+			if d.suffixStem(word, i, rule) {
+				return true
+			}
+		}
+	}
+	for _, rule := range d.suffixes[""] {
+		if d.suffixStem(word, len(word), rule) {
+			return true
+		}
+	}
+
+	// Prefixes alone
+	for i := 1; i <= len(word); i++ {
+		if i < len(word) && !utf8.RuneStart(word[i]) {
+			continue
+		}
+		for _, rule := range d.prefixes[word[:i]] {
+			stem := rule.strip + word[i:]
+			if stem != "" && matchCondition(rule.cond, stem, false) && d.hasFlag(stem, rule.flag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// suffixStem checks word with the suffix rule (whose text starts at byte i) removed
+func (d *hunspellDict) suffixStem(word string, i int, rule *affixRule) bool {
+	stem := word[:i] + rule.strip
+	if stem == "" || !matchCondition(rule.cond, stem, true) {
+		return false
+	}
+	if d.hasFlag(stem, rule.flag) {
+		return true
+	}
+	if !rule.cross {
+		return false
+	}
+
+	// Cross product: a prefix in front of the suffixed stem
+	for j := 1; j < len(stem); j++ {
+		if !utf8.RuneStart(stem[j]) {
+			continue
+		}
+		for _, prefix := range d.prefixes[stem[:j]] {
+			if !prefix.cross {
+				continue
+			}
+			root := prefix.strip + stem[j:]
+			if matchCondition(prefix.cond, root, false) && d.hasFlag(root, rule.flag) && d.hasFlag(root, prefix.flag) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// suggest returns likely corrections of a misspelled word
+func (d *hunspellDict) suggest(word string) []string {
+	var out []string
+	seen := map[string]bool{word: true}
+	add := func(candidate string) bool {
+		if seen[candidate] {
+			return false
+		}
+		seen[candidate] = true
+		for _, part := range strings.Fields(candidate) {
+			if !d.check(part) {
+				return false
+			}
+		}
+		out = append(out, candidate)
+		return len(out) >= SPELL_MAX_SUGGESTIONS
+	}
+
+	// Common misspellings from the REP table first
+	for _, rep := range d.rep {
+		for i := strings.Index(word, rep[0]); i >= 0; {
+			if add(word[:i] + rep[1] + word[i+len(rep[0]):]) {
+				return out
+			}
+			next := strings.Index(word[i+1:], rep[0])
+			if next < 0 {
+				break
+			}
+			i += next + 1
+		}
+	}
+
+	try := d.try
+	if try == "" {
+		try = "abcdefghijklmnopqrstuvwxyz"
+	}
+	runes := []rune(word)
+
+	for i := range runes {
+		// Transposed neighbours
+		if i+1 < len(runes) {
+			swapped := append([]rune(nil), runes...)
+			swapped[i], swapped[i+1] = swapped[i+1], swapped[i]
+			if add(string(swapped)) {
+				return out
+			}
+		}
+		// Extra character
+		if add(string(runes[:i]) + string(runes[i+1:])) {
+			return out
+		}
+	}
+	for i := range runes {
+		// Wrong character
+		for _, r := range try {
+			if r != runes[i] && add(string(runes[:i])+string(r)+string(runes[i+1:])) {
+				return out
+			}
+		}
+	}
+	for i := 0; i <= len(runes); i++ {
+		// Missing character
+		for _, r := range try {
+			if add(string(runes[:i]) + string(r) + string(runes[i:])) {
+				return out
+			}
+		}
+	}
+	for i := 1; i < len(runes); i++ {
+		// Missing space
+		if add(string(runes[:i]) + " " + string(runes[i:])) {
+			return out
+		}
+	}
+	return out
+}
+
+// =============================================================================
+// SPELL CHECKING
+// =============================================================================
+
+// spellWordRegex matches a word, allowing apostrophes inside it
+var spellWordRegex = regexp.MustCompile(`\p{L}+(?:['’]\p{L}+)*`)
+
+// spellSkipRegex matches text that is not prose: code spans, URLs, link targets,
+// [[wiki links]], e-mail addresses and #tags
+var spellSkipRegex = regexp.MustCompile("`[^`]*`?|(?:[a-zA-Z][a-zA-Z0-9+.-]*://|www\\.)\\S+|\\]\\([^)]*\\)?|\\[\\[[^\\]]*(?:\\]\\])?|\\S+@\\S+\\.\\S+|(?:^|\\s)#[\\p{L}\\p{N}_/-]+")
+
+// misspelledRanges returns the byte ranges of misspelled words in each line
+func (d *hunspellDict) misspelledRanges(lines []string) [][][2]int {
+	ranges := make([][][2]int, len(lines))
+	inFence := false
+	for i, line := range lines {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~") {
+			inFence = !inFence
+			continue
+		}
+		if inFence {
+			continue
+		}
+
+		skips := spellSkipRegex.FindAllStringIndex(line, -1)
+		for _, loc := range spellWordRegex.FindAllStringIndex(line, -1) {
+			if inRanges(skips, loc[0]) || utf8.RuneCountInString(line[loc[0]:loc[1]]) < 2 {
+				continue
+			}
+			word := strings.Replace(line[loc[0]:loc[1]], "’", "'", -1)
+			if !d.check(word) {
+				ranges[i] = append(ranges[i], [2]int{loc[0], loc[1]})
+			}
+		}
+	}
+	return ranges
+}
+
+// inRanges reports whether offset falls inside one of the ranges
+func inRanges(ranges [][]int, offset int) bool {
+	for _, r := range ranges {
+		if offset >= r[0] && offset < r[1] {
+			return true
+		}
+	}
+	return false
+}
+
+// personalDictPath returns the personal word list kept in the vault
+func (app *App) personalDictPath() string {
+	return filepath.Join(app.notesDir, PERSONAL_DICT_FILE)
+}
+
+// loadPersonalDict adds the vault's personal word list to the dictionary
+func (app *App) loadPersonalDict() {
+	content, err := ioutil.ReadFile(app.personalDictPath())
+	if err != nil {
+		return
+	}
+	for _, word := range strings.Split(string(content), "\n") {
+		if word = strings.TrimSpace(word); word != "" {
+			app.spell.personal[word] = true
+		}
+	}
+}
+
+// addPersonalWord accepts a word from now on and appends it to the personal list
+func (app *App) addPersonalWord(word string) error {
+	app.spell.personal[word] = true
+	app.spell.checked = make(map[string]bool)
+
+	file, err := os.OpenFile(app.personalDictPath(), os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	w := bufio.NewWriter(file)
+	fmt.Fprintln(w, word)
+	return w.Flush()
+}
+
+// scheduleSpellCheck underlines misspellings once typing pauses
+func (app *App) scheduleSpellCheck() {
+	if app.spell == nil || !app.isEditMode {
+		return
+	}
+
+	if app.spellTimer != nil {
+		app.spellTimer.Stop()
+	}
+	app.spellTimer = time.AfterFunc(SPELL_DEBOUNCE_MS*time.Millisecond, func() {
+		app.events.post(spellMsg{})
+	})
+}
+
+// underlineMisspellings redraws the edit buffer with misspelled words underlined
+func (app *App) underlineMisspellings() {
+	if app.spell == nil || !app.isEditMode || app.find.active || app.vim.painted.shown {
+		return // Find highlights and the visual selection own the buffer while shown
+	}
+	v, err := app.gui.View(MAIN_VIEW)
+	if err != nil {
+		return
+	}
+
+	lines := v.BufferLines()
+	ranges := app.spell.misspelledRanges(lines)
+	marked := make([]string, len(lines))
+	for i, line := range lines {
+		var b strings.Builder
+		last := 0
+		for _, r := range ranges[i] {
+			b.WriteString(line[last:r[0]])
+			b.WriteString(SPELL_ERROR_STYLE + line[r[0]:r[1]] + ANSI_RESET)
+			last = r[1]
+		}
+		b.WriteString(line[last:])
+		marked[i] = b.String()
+	}
+	app.setEditLines(v, marked)
+}
+
+// wordAtCursor returns the word under (or just before) the cursor and its rune range
+func wordAtCursor(v *gocui.View) (string, int, int, bool) {
+	x, y := v.Cursor()
+	text, _ := v.Line(y)
+	line := []rune(text)
+	if x > len(line) {
+		x = len(line)
+	}
+
+	for _, loc := range spellWordRegex.FindAllStringIndex(text, -1) {
+		start := utf8.RuneCountInString(text[:loc[0]])
+		end := start + utf8.RuneCountInString(text[loc[0]:loc[1]])
+		if x >= start && x <= end {
+			return string(line[start:end]), start, end, true
+		}
+	}
+	return "", 0, 0, false
+}
+
+// spellSuggest offers corrections for the word under the cursor
+func (app *App) spellSuggest(g *gocui.Gui, v *gocui.View) error {
+	if app.spell == nil || !app.isEditMode || !v.Editable {
+		return nil
+	}
+	word, start, end, ok := wordAtCursor(v)
+	if !ok {
+		return nil
+	}
+	_, y := v.Cursor()
+
+	var items []pickerItem
+	if !app.spell.check(strings.Replace(word, "’", "'", -1)) {
+		for _, s := range app.spell.suggest(word) {
+			items = append(items, pickerItem{label: s, value: s})
+		}
+	}
+	addLabel := fmt.Sprintf("Add \"%s\" to the dictionary", word)
+	items = append(items, pickerItem{label: addLabel, value: word})
+
+	return app.openPicker(" Spelling: "+word+" ", items, func(item *pickerItem, query string) error {
+		if item == nil {
+			return nil
+		}
+		mainView, err := g.View(MAIN_VIEW)
+		if err != nil {
+			return nil
+		}
+
+		if item.label == addLabel {
+			if err := app.addPersonalWord(word); err != nil {
+				return err
+			}
+		} else {
+			lines, _, _ := editBufferLines(mainView)
+			line := []rune(lines[y])
+			if end > len(line) || string(line[start:end]) != word {
+				return nil // The line changed meanwhile
+			}
+			lines[y] = string(line[:start]) + item.value + string(line[end:])
+			app.setEditLinesAt(mainView, lines, start+len([]rune(item.value)), y)
+		}
+		app.underlineMisspellings()
+		return nil
+	})
+}
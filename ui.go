@@ -23,6 +23,10 @@ func (app *App) layout(g *gocui.Gui) error {
 		app.lastResizeTime = time.Now()
 	}
 
+	// Keep the live preview level with the editor cursor, and the gutter with its rows
+	app.syncPreviewScroll()
+	app.updateGutter()
+
 	// Handle dynamic resize with debouncing
 	now := time.Now()
 	timeSinceLastResize := now.Sub(app.lastResizeTime)
@@ -67,39 +71,48 @@ func (app *App) layout(g *gocui.Gui) error {
 
 	if isSmallScreen {
 		// Small screen: respect user's manual toggle choice
+		g.DeleteView(PREVIEW_VIEW) // No room for the live preview
 		if app.sidebarVisible {
 			// Show only sidebar, hide main view
 			g.DeleteView(MAIN_VIEW)
+			g.DeleteView(GUTTER_VIEW)
 			if v, err := g.SetView(SIDEBAR_VIEW, 0, 3, maxX-1, maxY-3, 0); err != nil {
 				if err != gocui.ErrUnknownView {
 					return err
 				}
 				toggleHint := ""
 				if app.sidebarToggled {
-					toggleHint = " (Tab to toggle)"
+					toggleHint = " (" + app.keyHint(GLOBAL_SCOPE, "toggle_sidebar") + " to toggle)"
 				}
 				v.Title = " Notes" + toggleHint
 				v.Highlight = true
 				v.SelBgColor = gocui.ColorGreen
 				v.SelFgColor = gocui.ColorBlack
+				v.Editable = true // Letters go to the type-ahead
+				v.Editor = app.sidebarEditor()
 			}
 		} else {
 			// Show only main view, hide sidebar
 			g.DeleteView(SIDEBAR_VIEW)
-			if v, err := g.SetView(MAIN_VIEW, 0, 3, maxX-1, maxY-3, 0); err != nil {
+			x0, err := app.layoutGutter(g, 0, 3, maxY-3)
+			if err != nil {
+				return err
+			}
+			if v, err := g.SetView(MAIN_VIEW, x0, 3, maxX-1, maxY-3, 0); err != nil {
 				if err != gocui.ErrUnknownView {
 					return err
 				}
 				toggleHint := ""
 				if app.sidebarToggled {
-					toggleHint = " (Tab to toggle)"
+					toggleHint = " (" + app.keyHint(GLOBAL_SCOPE, "toggle_sidebar") + " to toggle)"
 				}
-				title := " View Mode" + toggleHint + " - Press Enter to edit"
+				title := " View Mode" + toggleHint + " - " + app.viewModeHints()
 				if app.isEditMode {
-					title = " Edit Mode" + toggleHint + " - Press Esc to view, Ctrl+S to save"
+					title = " Edit Mode" + toggleHint + " - " + app.editModeHints()
 				}
 				v.Title = title
 				v.Editable = app.isEditMode
+				v.Editor = app.mainEditor()
 				v.Wrap = true
 			}
 		}
@@ -123,22 +136,13 @@ func (app *App) layout(g *gocui.Gui) error {
 			v.Highlight = true
 			v.SelBgColor = gocui.ColorGreen
 			v.SelFgColor = gocui.ColorBlack
+			v.Editable = true // Letters go to the type-ahead
+			v.Editor = app.sidebarEditor()
 		}
 
-		// Main view (right panel)
-		if v, err := g.SetView(MAIN_VIEW, app.sidebarWidth+1, 3, maxX-1, maxY-3, 0); err != nil {
-			if err != gocui.ErrUnknownView {
-				return err
-			}
-			if app.isEditMode {
-				v.Title = " Edit Mode - Press Esc to view, Ctrl+S to save "
-				v.Editable = true
-				v.Wrap = true
-			} else {
-				v.Title = " View Mode - Press Enter to edit "
-				v.Editable = false
-				v.Wrap = true
-			}
+		// Main view (right panel), with the live preview beside it while editing
+		if err := app.layoutMainArea(g, app.sidebarWidth+1, 3, maxX-1, maxY-3); err != nil {
+			return err
 		}
 	}
 
@@ -151,11 +155,28 @@ func (app *App) layout(g *gocui.Gui) error {
 		app.updateStatusBar()
 	}
 
+	// Find bar
+	if app.find.active {
+		if err := app.layoutFindBar(g); err != nil {
+			return err
+		}
+	}
+
+	// Picker overlay
+	if app.picker.active {
+		if err := app.layoutPicker(g); err != nil {
+			return err
+		}
+	}
+
 	// Handle input dialog
 	if app.showingDialog {
 		return app.layoutInputDialog(g)
 	}
 
+	// The list only holds the rows in view, so it is redrawn when they change
+	app.syncSidebarRows()
+
 	// Initialize content only on first layout or when forced
 	if !app.viewsInitialized || app.forceLayout {
 		app.updateSidebar()
@@ -225,24 +246,6 @@ func (app *App) updateHeaderWithWidth(screenWidth int) {
 	fmt.Fprintf(v, "%s%s", strings.Repeat(" ", padding), fullHeader)
 }
 
-// updateSidebar refreshes the sidebar content
-func (app *App) updateSidebar() {
-	v, err := app.gui.View(SIDEBAR_VIEW)
-	if err != nil {
-		return
-	}
-
-	v.Clear()
-
-	for i, item := range app.items {
-		if i == app.currentItem {
-			fmt.Fprintf(v, "> %s\n", item.Title)
-		} else {
-			fmt.Fprintf(v, "  %s\n", item.Title)
-		}
-	}
-}
-
 // updateMainView refreshes the main view content
 func (app *App) updateMainView() {
 	v, err := app.gui.View(MAIN_VIEW)
@@ -251,27 +254,84 @@ func (app *App) updateMainView() {
 	}
 
 	if app.isEditMode {
-		title := " Edit Mode (Raw Markdown) - ↑/↓: Cursor movement, Ctrl+C/V: Copy/Paste, Esc to view, Ctrl+S to save"
-		if app.isLargeFile {
-			title = " Edit Mode - Large file editing disabled - ↑/↓: Cursor movement"
+		title := " Edit Mode (Raw Markdown) - " + app.formatKeyHints(", ",
+			keyHintSpec{MAIN_VIEW, []string{"scroll_up", "scroll_down"}, "Cursor movement"},
+			keyHintSpec{MAIN_VIEW, []string{"copy", "paste"}, "Copy/Paste"},
+		) + ", " + app.editModeHints()
+		if app.vimEnabled {
+			title = " Edit Mode (Vim) - i: Insert, Esc: Normal, :w to save, :q to view"
+		}
+		if app.largeEdit != nil {
+			title = " Edit Mode - Large File - " + app.formatKeyHints(", ",
+				keyHintSpec{MAIN_VIEW, []string{"page_up", "page_down"}, "Page"},
+				keyHintSpec{MAIN_VIEW, []string{"go_to_line"}, "Go to line"},
+			) + ", " + app.editModeHints() + " "
 		}
 		v.Title = title
-		v.Editable = !app.isLargeFile // Disable editing for large files
+		v.Editable = true
 	} else {
-		title := " View Mode (Rendered Markdown) - Press Enter to edit, Tab to switch panels "
+		title := " View Mode (Rendered Markdown) - " + app.formatKeyHints(", ",
+			keyHintSpec{MAIN_VIEW, []string{"edit"}, "Edit"},
+			keyHintSpec{GLOBAL_SCOPE, []string{"toggle_sidebar"}, "Switch panels"},
+		) + " "
 		if app.isLargeFile {
-			title = fmt.Sprintf(" View Mode - Large File (Line %d/%d) - ↑/↓ to scroll, Enter to edit ",
-				app.currentLine+1, app.totalLines)
+			kind := "Large File"
+			if app.follow != nil {
+				kind = "Following"
+			}
+			title = fmt.Sprintf(" View Mode - %s (Line %d/%s) - %s ",
+				kind, app.currentLine+1, app.totalLinesLabel(), app.formatKeyHints(", ",
+					keyHintSpec{MAIN_VIEW, []string{"scroll_up", "scroll_down"}, "Scroll"},
+					keyHintSpec{MAIN_VIEW, []string{"external_edit"}, "$EDITOR"}))
+		}
+		if app.binary != nil {
+			title = " View Mode - Binary File - " + app.formatKeyHints(", ",
+				keyHintSpec{MAIN_VIEW, []string{"hexdump"}, "Hexdump"}) + " "
+			if app.binary.hexdump {
+				title = " View Mode - Hexdump - " + app.formatKeyHints(", ",
+					keyHintSpec{MAIN_VIEW, []string{"scroll_up", "scroll_down"}, "Scroll"},
+					keyHintSpec{MAIN_VIEW, []string{"page_up", "page_down"}, "Page"},
+					keyHintSpec{MAIN_VIEW, []string{"hexdump"}, "Summary"}) + " "
+			}
 		}
 		v.Title = title
 		v.Editable = false
 		v.Clear()
+		if app.binary != nil {
+			// Binary files are shown as they are, not as markdown
+			fmt.Fprint(v, app.currentContent)
+			return
+		}
 		// Render markdown in view mode
-		renderedContent := app.renderMarkdown(app.currentContent)
+		doc := app.renderDocument(app.currentContent, 0)
+		renderedContent := doc.text
+		if app.find.active {
+			firstLine := 0
+			if app.isLargeFile {
+				firstLine = app.currentLine
+			}
+			renderedContent = strings.Join(app.highlightFindMatches(doc.lines, firstLine), "\n")
+		}
 		fmt.Fprint(v, renderedContent)
 	}
 }
 
+// viewModeHints returns the key hints shown in the main view title in view mode
+func (app *App) viewModeHints() string {
+	return app.formatKeyHints(", ",
+		keyHintSpec{MAIN_VIEW, []string{"edit"}, "Edit"},
+		keyHintSpec{MAIN_VIEW, []string{"external_edit"}, "$EDITOR"},
+		keyHintSpec{MAIN_VIEW, []string{"find"}, "Find"})
+}
+
+// editModeHints returns the key hints shown in the main view title in edit mode
+func (app *App) editModeHints() string {
+	return app.formatKeyHints(", ",
+		keyHintSpec{MAIN_VIEW, []string{"exit_edit"}, "View"},
+		keyHintSpec{MAIN_VIEW, []string{"save"}, "Save"},
+		keyHintSpec{MAIN_VIEW, []string{"find"}, "Find"})
+}
+
 // updateStatusBar refreshes the status bar
 func (app *App) updateStatusBar() {
 	v, err := app.gui.View(STATUS_VIEW)
@@ -283,6 +343,9 @@ func (app *App) updateStatusBar() {
 	mode := "VIEW"
 	if app.isEditMode {
 		mode = "EDIT"
+		if app.vimEnabled {
+			mode = app.vim.mode.String()
+		}
 	}
 
 	currentPanel := "SIDEBAR"
@@ -298,14 +361,41 @@ func (app *App) updateStatusBar() {
 	}
 
 	chunkInfo := ""
-	if app.isLargeFile {
-		chunkInfo = fmt.Sprintf(" | Line: %d/%d | ↑/↓: Scroll", app.currentLine+1, app.totalLines)
+	if app.largeEdit != nil {
+		if v, err := app.gui.View(MAIN_VIEW); err == nil {
+			chunkInfo = fmt.Sprintf(" | Line: %d/%d", app.largeEditLine(v)+1, app.largeEditLines(v))
+		}
+	} else if app.binary != nil && app.binary.hexdump {
+		chunkInfo = fmt.Sprintf(" | Offset: 0x%08x/%s", app.binary.offset, formatSize(app.binary.size))
+	} else if app.isLargeFile {
+		chunkInfo = fmt.Sprintf(" | Line: %d/%s", app.currentLine+1, app.totalLinesLabel()) + app.indexProgress()
+		if app.follow != nil && app.follow.auto {
+			chunkInfo += " | Following"
+		} else if app.follow != nil {
+			chunkInfo += " | Following (paused)"
+		}
+	}
+
+	// Letters typed to jump to an item in the sidebar
+	jumpInfo := ""
+	if app.sidebar.typing {
+		jumpInfo = " | Jump: " + app.sidebar.typed + " (" + app.formatKeyHints(", ",
+			keyHintSpec{SIDEBAR_VIEW, []string{"select_item"}, "Open"}) + ", Esc: Stop)"
+	}
+
+	// A one-off message, such as a failed external editor
+	noteInfo := ""
+	if app.statusNote != "" {
+		noteInfo = " | " + app.statusNote
 	}
 
 	// Add navigation hints
 	navHints := ""
 	if app.isLargeFile {
-		navHints = " | ↑/↓: Scroll | PgUp/PgDn: Page | Home/End: Top/Bottom"
+		navHints = " | " + app.formatKeyHints(" | ",
+			keyHintSpec{MAIN_VIEW, []string{"scroll_up", "scroll_down"}, "Scroll"},
+			keyHintSpec{MAIN_VIEW, []string{"page_up", "page_down"}, "Page"},
+			keyHintSpec{MAIN_VIEW, []string{"go_to_top", "go_to_bottom"}, "Top/Bottom"})
 	}
 
 	// Add toggle hint for small screens (only if user manually toggled)
@@ -313,7 +403,8 @@ func (app *App) updateStatusBar() {
 	if app.gui != nil && app.sidebarToggled {
 		maxX, _ := app.gui.Size()
 		if maxX < SMALL_SCREEN_WIDTH {
-			toggleHint = " | Tab: Toggle Sidebar"
+			toggleHint = " | " + app.formatKeyHints(" | ",
+				keyHintSpec{GLOBAL_SCOPE, []string{"toggle_sidebar"}, "Toggle Sidebar"})
 		}
 	}
 
@@ -323,7 +414,14 @@ func (app *App) updateStatusBar() {
 		resizeInfo = " | Resizing..."
 	}
 
-	status := fmt.Sprintf(" Mode: %s | Panel: %s | Item: %s | Items: %d%s%s%s%s | d: Delete | r: Rename | Ctrl+N: New | F5/Ctrl+R: Refresh",
-		mode, currentPanel, currentItemName, len(app.items), chunkInfo, navHints, toggleHint, resizeInfo)
+	keyHints := app.formatKeyHints(" | ",
+		keyHintSpec{SIDEBAR_VIEW, []string{"delete_item"}, "Delete"},
+		keyHintSpec{SIDEBAR_VIEW, []string{"rename_item"}, "Rename"},
+		keyHintSpec{GLOBAL_SCOPE, []string{"new_note"}, "New"},
+		keyHintSpec{GLOBAL_SCOPE, []string{"refresh"}, "Refresh"},
+		keyHintSpec{GLOBAL_SCOPE, []string{"quit"}, "Quit"})
+
+	status := fmt.Sprintf(" Mode: %s%s | Panel: %s | Item: %s | Items: %d%s%s%s%s%s%s | %s",
+		mode, app.vimStatus(), currentPanel, currentItemName, len(app.items), chunkInfo, jumpInfo, noteInfo, navHints, toggleHint, resizeInfo, keyHints)
 	fmt.Fprint(v, status)
 }
@@ -0,0 +1,865 @@
+package main
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+// =============================================================================
+// VIM-STYLE MODAL EDITING
+// =============================================================================
+
+// vimMode identifies the active modal editing mode
+type vimMode int
+
+const (
+	vimNormal vimMode = iota
+	vimInsert
+	vimVisual
+	vimVisualLine
+)
+
+// String returns the status bar label for the mode
+func (m vimMode) String() string {
+	switch m {
+	case vimInsert:
+		return "INSERT"
+	case vimVisual:
+		return "VISUAL"
+	case vimVisualLine:
+		return "V-LINE"
+	default:
+		return "NORMAL"
+	}
+}
+
+// vimPos is a position in the edit buffer (rune column, buffer line)
+type vimPos struct {
+	x, y int
+}
+
+// vimState holds the modal editor state for the main view
+type vimState struct {
+	mode      vimMode
+	count     int          // count typed before a command
+	operator  rune         // pending operator (d, c, y) or 0
+	opCount   int          // count typed before the pending operator
+	pendingG  bool         // first 'g' of "gg" typed
+	gCount    int          // count typed before the pending 'g'
+	cmdPrefix rune         // ':' or '/' while typing a command line, 0 otherwise
+	cmdline   string       // command line typed so far
+	register  string       // unnamed register used by d, c, y and p
+	linewise  bool         // register holds whole lines
+	search    string       // last search pattern
+	anchor    vimPos       // visual mode start position
+	message   string       // feedback from the last command
+	painted   vimSelection // visual selection drawn in the buffer
+}
+
+// vimSelection is a visual selection, from its first to its last position
+type vimSelection struct {
+	from, to vimPos
+	linewise bool
+	shown    bool // false when nothing is selected
+}
+
+// covers reports whether line y is selected from end to end
+func (sel vimSelection) covers(y int) bool {
+	if !sel.shown {
+		return false
+	}
+	if sel.linewise {
+		return y >= sel.from.y && y <= sel.to.y
+	}
+	return y > sel.from.y && y < sel.to.y
+}
+
+// resetVim puts the modal editor back into normal mode with nothing pending
+func (app *App) resetVim() {
+	register, linewise, search, painted := app.vim.register, app.vim.linewise, app.vim.search, app.vim.painted
+	app.vim = vimState{register: register, linewise: linewise, search: search, painted: painted}
+}
+
+// vimStatus returns the command line or last message for the status bar
+func (app *App) vimStatus() string {
+	if !app.vimEnabled || !app.isEditMode {
+		return ""
+	}
+	if app.vim.cmdPrefix != 0 {
+		return " | " + string(app.vim.cmdPrefix) + app.vim.cmdline
+	}
+	if app.vim.message != "" {
+		return " | " + app.vim.message
+	}
+	if sel := app.vim.painted; sel.shown {
+		if sel.linewise || sel.from.y != sel.to.y {
+			return fmt.Sprintf(" | %d lines selected", sel.to.y-sel.from.y+1)
+		}
+		return fmt.Sprintf(" | %d chars selected", sel.to.x-sel.from.x+1)
+	}
+	return ""
+}
+
+// =============================================================================
+// KEY HANDLING
+// =============================================================================
+
+// vimEdit is the gocui editor used for the main view when Vim mode is enabled
+func (app *App) vimEdit(v *gocui.View, key gocui.Key, ch rune, mod gocui.Modifier) {
+	switch {
+	case app.vim.cmdPrefix != 0:
+		app.vimCmdlineKey(key, ch)
+	case app.vim.mode == vimInsert:
+		gocui.DefaultEditor.Edit(v, key, ch, mod)
+	default:
+		app.vimNormalKey(v, key, ch)
+	}
+	app.vimPaintSelection(v)
+	app.updateStatusBar()
+}
+
+// vimEscape handles Esc: leaves insert/visual mode and cancels pending input
+func (app *App) vimEscape(v *gocui.View) {
+	wasInsert := app.vim.mode == vimInsert
+	wasVisual := app.vim.painted.shown
+	app.resetVim()
+	app.vimPaintSelection(v)
+	if wasVisual {
+		app.scheduleSpellCheck() // Underlines are redrawn once the selection is gone
+	}
+
+	if wasInsert {
+		// Like Vim, step back onto the last inserted character
+		cx, cy := v.Cursor()
+		lines := vimBufferLines(v)
+		app.vimSetCursor(v, lines, vimPos{cx - 1, cy})
+	}
+	app.updateStatusBar()
+}
+
+// vimHandleEnter handles Enter outside insert mode
+func (app *App) vimHandleEnter(v *gocui.View) error {
+	if app.vim.cmdPrefix != 0 {
+		err := app.vimExecuteCmdline(v)
+		app.updateStatusBar()
+		return err
+	}
+
+	// Enter moves to the first non-blank of the next line
+	lines := vimBufferLines(v)
+	_, cy := v.Cursor()
+	if cy+1 < len(lines) {
+		app.vimSetCursor(v, lines, vimPos{vimFirstNonBlank(lines[cy+1]), cy + 1})
+	}
+	return nil
+}
+
+// vimCmdlineKey edits the ':' or '/' command line
+func (app *App) vimCmdlineKey(key gocui.Key, ch rune) {
+	s := &app.vim
+	switch {
+	case ch != 0:
+		s.cmdline += string(ch)
+	case key == gocui.KeySpace:
+		s.cmdline += " "
+	case key == gocui.KeyBackspace || key == gocui.KeyBackspace2:
+		if s.cmdline == "" {
+			s.cmdPrefix = 0
+			return
+		}
+		r := []rune(s.cmdline)
+		s.cmdline = string(r[:len(r)-1])
+	}
+}
+
+// vimExecuteCmdline runs the command line typed after ':' or '/'
+func (app *App) vimExecuteCmdline(v *gocui.View) error {
+	s := &app.vim
+	prefix, cmd := s.cmdPrefix, strings.TrimSpace(s.cmdline)
+	s.cmdPrefix, s.cmdline = 0, ""
+
+	if prefix == '/' {
+		if cmd != "" {
+			s.search = cmd
+		}
+		app.vimSearchNext(v, true, 1)
+		return nil
+	}
+
+	g := app.gui
+	switch cmd {
+	case "":
+		return nil
+	case "w":
+		return app.saveNote(g, v)
+	case "q":
+		return app.exitEditMode(g, v)
+	case "wq", "x":
+		if err := app.saveNote(g, v); err != nil {
+			return err
+		}
+		return app.exitEditMode(g, v)
+	case "q!":
+		// Discard changes and leave edit mode
+		v.Clear()
+		fmt.Fprint(v, app.originalContent)
+		return app.doExitEditMode(g, v)
+	}
+
+	if line, err := strconv.Atoi(cmd); err == nil {
+		lines := vimBufferLines(v)
+		y := vimClampLine(lines, line-1)
+		app.vimSetCursor(v, lines, vimPos{vimFirstNonBlank(lines[y]), y})
+		return nil
+	}
+
+	s.message = "Not an editor command: " + cmd
+	return nil
+}
+
+// vimNormalKey handles a key in normal or visual mode
+func (app *App) vimNormalKey(v *gocui.View, key gocui.Key, ch rune) {
+	if ch == 0 {
+		switch key {
+		case gocui.KeySpace, gocui.KeyArrowRight:
+			ch = 'l'
+		case gocui.KeyBackspace, gocui.KeyBackspace2, gocui.KeyArrowLeft:
+			ch = 'h'
+		default:
+			return
+		}
+	}
+
+	s := &app.vim
+	s.message = ""
+
+	// Accumulate counts ("0" is a motion unless a count is in progress)
+	if (ch >= '1' && ch <= '9') || (ch == '0' && s.count > 0) {
+		s.count = s.count*10 + int(ch-'0')
+		return
+	}
+	count := s.count
+	s.count = 0
+
+	lines := vimBufferLines(v)
+	cx, cy := v.Cursor()
+	pos := vimClamp(lines, vimPos{cx, cy}, true)
+
+	// Counts before and after an operator multiply ("2d3w" deletes 6 words)
+	if s.operator != 0 && (count > 0 || s.opCount > 0) {
+		count = vimCount(count) * vimCount(s.opCount)
+	}
+
+	if s.pendingG {
+		s.pendingG = false
+		if ch != 'g' {
+			s.operator = 0
+			return
+		}
+		target := vimPos{0, 0}
+		if s.gCount > 0 {
+			target.y = vimClampLine(lines, s.gCount-1)
+		}
+		target.x = vimFirstNonBlank(lines[target.y])
+		app.vimMotionDone(v, lines, pos, target, true, false)
+		return
+	}
+
+	if target, linewise, inclusive, ok := vimMotion(lines, pos, ch, count, s.operator); ok {
+		app.vimMotionDone(v, lines, pos, target, linewise, inclusive)
+		return
+	}
+
+	visual := s.mode == vimVisual || s.mode == vimVisualLine
+	n := vimCount(count)
+
+	switch ch {
+	case 'g':
+		s.pendingG = true
+		s.gCount = count
+	case 'd', 'c', 'y', 'x':
+		if visual {
+			if ch == 'x' {
+				ch = 'd'
+			}
+			app.vimOperate(v, lines, ch, s.anchor, pos, s.mode == vimVisualLine, true)
+			if ch != 'c' {
+				s.mode = vimNormal
+			}
+			return
+		}
+		if ch == 'x' {
+			if len(lines[pos.y]) > 0 {
+				end := vimPos{pos.x + n, pos.y}
+				app.vimOperate(v, lines, 'd', pos, vimClamp(lines, end, false), false, false)
+			}
+			return
+		}
+		if s.operator == ch {
+			// dd, cc, yy operate on count whole lines
+			s.operator = 0
+			end := vimPos{pos.x, vimClampLine(lines, pos.y+n-1)}
+			app.vimOperate(v, lines, ch, pos, end, true, false)
+			return
+		}
+		s.operator = ch
+		s.opCount = count
+	case 'D', 'C':
+		op := unicode.ToLower(ch)
+		app.vimOperate(v, lines, op, pos, vimPos{len(lines[pos.y]), pos.y}, false, false)
+	case 'p', 'P':
+		app.vimPut(v, lines, pos, ch == 'p', n)
+	case 'i':
+		app.vimInsertAt(v, lines, pos)
+	case 'a':
+		if len(lines[pos.y]) > 0 {
+			pos.x++
+		}
+		app.vimInsertAt(v, lines, pos)
+	case 'I':
+		app.vimInsertAt(v, lines, vimPos{vimFirstNonBlank(lines[pos.y]), pos.y})
+	case 'A':
+		app.vimInsertAt(v, lines, vimPos{len(lines[pos.y]), pos.y})
+	case 'o', 'O':
+		y := pos.y
+		if ch == 'o' {
+			y++
+		}
+		lines = append(lines[:y], append([][]rune{{}}, lines[y:]...)...)
+		s.mode = vimInsert
+		app.vimSetLines(v, lines, vimPos{0, y})
+	case 'v', 'V':
+		mode := vimVisual
+		if ch == 'V' {
+			mode = vimVisualLine
+		}
+		if s.mode == mode {
+			s.mode = vimNormal
+		} else {
+			if !visual {
+				s.anchor = pos
+			}
+			s.mode = mode
+		}
+	case 'n', 'N':
+		app.vimSearchNext(v, ch == 'n', n)
+	case '/', ':':
+		s.operator = 0
+		s.cmdPrefix = ch
+		s.cmdline = ""
+	default:
+		s.operator = 0
+	}
+}
+
+// vimMotionDone moves the cursor to target, or applies the pending operator
+func (app *App) vimMotionDone(v *gocui.View, lines [][]rune, from, to vimPos, linewise, inclusive bool) {
+	s := &app.vim
+	if s.operator != 0 {
+		op := s.operator
+		s.operator = 0
+		app.vimOperate(v, lines, op, from, to, linewise, inclusive)
+		return
+	}
+	app.vimSetCursor(v, lines, to)
+}
+
+// vimInsertAt switches to insert mode with the cursor at pos
+func (app *App) vimInsertAt(v *gocui.View, lines [][]rune, pos vimPos) {
+	app.vim.mode = vimInsert
+	app.vimSetCursor(v, lines, pos)
+}
+
+// =============================================================================
+// OPERATORS
+// =============================================================================
+
+// vimOperate applies operator op (d, c or y) to the text between from and to
+func (app *App) vimOperate(v *gocui.View, lines [][]rune, op rune, from, to vimPos, linewise, inclusive bool) {
+	s := &app.vim
+
+	if linewise {
+		y1, y2 := from.y, to.y
+		if y1 > y2 {
+			y1, y2 = y2, y1
+		}
+		removed := make([]string, 0, y2-y1+1)
+		for _, line := range lines[y1 : y2+1] {
+			removed = append(removed, string(line))
+		}
+		s.register = strings.Join(removed, "\n")
+		s.linewise = true
+
+		switch op {
+		case 'y':
+			app.vimSetCursor(v, lines, vimPos{from.x, y1})
+		case 'd':
+			lines = append(lines[:y1], lines[y2+1:]...)
+			if len(lines) == 0 {
+				lines = [][]rune{{}}
+			}
+			y := vimClampLine(lines, y1)
+			app.vimSetLines(v, lines, vimPos{vimFirstNonBlank(lines[y]), y})
+		case 'c':
+			lines = append(lines[:y1], append([][]rune{{}}, lines[y2+1:]...)...)
+			s.mode = vimInsert
+			app.vimSetLines(v, lines, vimPos{0, y1})
+		}
+		return
+	}
+
+	start, end := from, to
+	if vimOffset(lines, start) > vimOffset(lines, end) {
+		start, end = end, start
+	}
+
+	a, b := vimOffset(lines, start), vimOffset(lines, end)
+	if inclusive {
+		b++
+	} else if end.x == 0 && end.y > start.y {
+		// An exclusive motion ending in column 0 stops at the end of the previous line
+		b = vimOffset(lines, vimPos{len(lines[end.y-1]), end.y - 1})
+	}
+
+	flat := vimFlatten(lines)
+	if b > len(flat) {
+		b = len(flat)
+	}
+	if a > b {
+		a = b
+	}
+	s.register = string(flat[a:b])
+	s.linewise = false
+
+	if op == 'y' {
+		app.vimSetCursor(v, lines, start)
+		return
+	}
+
+	flat = append(flat[:a:a], flat[b:]...)
+	lines = vimSplit(flat)
+	if op == 'c' {
+		s.mode = vimInsert
+	}
+	app.vimSetLines(v, lines, vimPosAt(lines, a))
+}
+
+// vimPut pastes the unnamed register after (p) or before (P) the cursor
+func (app *App) vimPut(v *gocui.View, lines [][]rune, pos vimPos, after bool, count int) {
+	s := &app.vim
+	if s.register == "" && !s.linewise {
+		return
+	}
+
+	if s.linewise {
+		var inserted [][]rune
+		for i := 0; i < count; i++ {
+			for _, line := range strings.Split(s.register, "\n") {
+				inserted = append(inserted, []rune(line))
+			}
+		}
+		y := pos.y
+		if after {
+			y++
+		}
+		lines = append(lines[:y], append(inserted, lines[y:]...)...)
+		app.vimSetLines(v, lines, vimPos{vimFirstNonBlank(lines[y]), y})
+		return
+	}
+
+	text := []rune(strings.Repeat(s.register, count))
+	off := vimOffset(lines, pos)
+	if after && len(lines[pos.y]) > 0 {
+		off++
+	}
+	flat := vimFlatten(lines)
+	flat = append(flat[:off:off], append(text, flat[off:]...)...)
+	lines = vimSplit(flat)
+	app.vimSetLines(v, lines, vimPosAt(lines, off+len(text)-1))
+}
+
+// vimSearchNext jumps to the count'th next (or previous) match of the last search
+func (app *App) vimSearchNext(v *gocui.View, forward bool, count int) {
+	s := &app.vim
+	if s.search == "" {
+		return
+	}
+
+	lines := vimBufferLines(v)
+	cx, cy := v.Cursor()
+	pos := vimPos{cx, cy}
+	for i := 0; i < count; i++ {
+		next, ok := vimFind(lines, pos, s.search, forward)
+		if !ok {
+			s.message = "Pattern not found: " + s.search
+			return
+		}
+		pos = next
+	}
+	app.vimSetCursor(v, lines, pos)
+}
+
+// =============================================================================
+// BUFFER HELPERS
+// =============================================================================
+
+// vimSetCursor places the cursor at pos and scrolls the view to keep it visible
+func (app *App) vimSetCursor(v *gocui.View, lines [][]rune, pos vimPos) {
+	pos = vimClamp(lines, pos, app.vim.mode != vimInsert)
+	v.SetCursor(pos.x, pos.y)
+	v.MoveCursor(0, 0) // Adjusts the origin so the cursor is on screen
+}
+
+// vimSetLines replaces the view content and places the cursor at pos
+func (app *App) vimSetLines(v *gocui.View, lines [][]rune, pos vimPos) {
+	app.vim.painted = vimSelection{} // The new content is drawn plain
+	ox, oy := v.Origin()
+	v.Clear()
+	fmt.Fprint(v, string(vimFlatten(lines)))
+	v.SetOrigin(ox, oy)
+	app.vimSetCursor(v, lines, pos)
+}
+
+// vimPaintSelection draws the visual selection from the anchor to the cursor.
+// Only lines whose highlight changed are rewritten, so moving the cursor
+// doesn't repaint a long selection.
+func (app *App) vimPaintSelection(v *gocui.View) {
+	s := &app.vim
+	sel := vimSelection{}
+	if s.mode == vimVisual || s.mode == vimVisualLine {
+		cx, cy := v.Cursor()
+		sel = vimSelection{from: s.anchor, to: vimPos{cx, cy}, linewise: s.mode == vimVisualLine, shown: true}
+		if sel.to.y < sel.from.y || (sel.to.y == sel.from.y && sel.to.x < sel.from.x) {
+			sel.from, sel.to = sel.to, sel.from
+		}
+	}
+	old := s.painted
+	if !old.shown && !sel.shown {
+		return
+	}
+
+	first, last := sel.from.y, sel.to.y
+	if !sel.shown || (old.shown && old.from.y < first) {
+		first = old.from.y
+	}
+	if !sel.shown || (old.shown && old.to.y > last) {
+		last = old.to.y
+	}
+	for y := first; y <= last; y++ {
+		if old.covers(y) && sel.covers(y) {
+			continue // Selected end to end before and after
+		}
+		line := []rune(viewLine(v, y))
+		if sel.shown && y >= sel.from.y && y <= sel.to.y {
+			start, end := 0, len(line)
+			if !sel.linewise && y == sel.from.y {
+				start = sel.from.x
+			}
+			if !sel.linewise && y == sel.to.y {
+				end = sel.to.x + 1 // The cursor's character is selected too
+			}
+			if end > len(line) {
+				end = len(line)
+			}
+			if start > end {
+				start = end
+			}
+			v.SetLine(y, string(line[:start])+VIM_VISUAL_STYLE+string(line[start:end])+ANSI_RESET+string(line[end:]))
+		} else {
+			v.SetLine(y, string(line))
+		}
+	}
+	s.painted = sel
+}
+
+// vimBufferLines returns the view's buffer as lines of runes
+func vimBufferLines(v *gocui.View) [][]rune {
+	var lines [][]rune
+	for _, line := range v.BufferLines() {
+		lines = append(lines, []rune(line))
+	}
+	if len(lines) == 0 {
+		lines = [][]rune{{}}
+	}
+	return lines
+}
+
+// vimFlatten joins lines into a single rune slice separated by newlines
+func vimFlatten(lines [][]rune) []rune {
+	var flat []rune
+	for i, line := range lines {
+		if i > 0 {
+			flat = append(flat, '\n')
+		}
+		flat = append(flat, line...)
+	}
+	return flat
+}
+
+// vimSplit splits a flattened rune slice back into lines
+func vimSplit(flat []rune) [][]rune {
+	lines := [][]rune{{}}
+	for _, r := range flat {
+		if r == '\n' {
+			lines = append(lines, []rune{})
+			continue
+		}
+		lines[len(lines)-1] = append(lines[len(lines)-1], r)
+	}
+	return lines
+}
+
+// vimOffset converts a position to an offset into the flattened buffer
+func vimOffset(lines [][]rune, p vimPos) int {
+	off := 0
+	for i := 0; i < p.y && i < len(lines); i++ {
+		off += len(lines[i]) + 1
+	}
+	return off + p.x
+}
+
+// vimPosAt converts an offset into the flattened buffer to a position
+func vimPosAt(lines [][]rune, off int) vimPos {
+	for y, line := range lines {
+		if off <= len(line) {
+			return vimPos{off, y}
+		}
+		off -= len(line) + 1
+	}
+	last := len(lines) - 1
+	return vimPos{len(lines[last]), last}
+}
+
+// vimClamp keeps pos inside the buffer; normal mode may not sit past the last character
+func vimClamp(lines [][]rune, p vimPos, normal bool) vimPos {
+	p.y = vimClampLine(lines, p.y)
+	maxX := len(lines[p.y])
+	if normal && maxX > 0 {
+		maxX--
+	}
+	if p.x > maxX {
+		p.x = maxX
+	}
+	if p.x < 0 {
+		p.x = 0
+	}
+	return p
+}
+
+// vimClampLine keeps a line index inside the buffer
+func vimClampLine(lines [][]rune, y int) int {
+	if y >= len(lines) {
+		y = len(lines) - 1
+	}
+	if y < 0 {
+		y = 0
+	}
+	return y
+}
+
+// vimCount returns the count to use for a command, defaulting to 1
+func vimCount(count int) int {
+	if count < 1 {
+		return 1
+	}
+	return count
+}
+
+// vimFirstNonBlank returns the column of the first non-blank character
+func vimFirstNonBlank(line []rune) int {
+	for i, r := range line {
+		if !unicode.IsSpace(r) {
+			return i
+		}
+	}
+	return 0
+}
+
+// =============================================================================
+// MOTIONS
+// =============================================================================
+
+// vimMotion computes the target of a motion key; ok is false if ch is not a motion
+func vimMotion(lines [][]rune, pos vimPos, ch rune, count int, operator rune) (target vimPos, linewise, inclusive, ok bool) {
+	n := vimCount(count)
+	switch ch {
+	case 'h':
+		return vimPos{pos.x - n, pos.y}, false, false, true
+	case 'l':
+		return vimClamp(lines, vimPos{pos.x + n, pos.y}, false), false, false, true
+	case 'j':
+		return vimPos{pos.x, vimClampLine(lines, pos.y+n)}, true, false, true
+	case 'k':
+		return vimPos{pos.x, vimClampLine(lines, pos.y-n)}, true, false, true
+	case '0':
+		return vimPos{0, pos.y}, false, false, true
+	case '^':
+		return vimPos{vimFirstNonBlank(lines[pos.y]), pos.y}, false, false, true
+	case '$':
+		y := vimClampLine(lines, pos.y+n-1)
+		return vimPos{len(lines[y]), y}, false, false, true
+	case 'G':
+		y := len(lines) - 1
+		if count > 0 {
+			y = vimClampLine(lines, count-1)
+		}
+		return vimPos{vimFirstNonBlank(lines[y]), y}, true, false, true
+	case 'w':
+		// "cw" on a word behaves like "ce"
+		if operator == 'c' && vimClass(vimCharAt(lines, pos)) != 0 {
+			return vimMotion(lines, pos, 'e', count, 0)
+		}
+		for i := 0; i < n; i++ {
+			pos = vimWordForward(lines, pos)
+		}
+		return pos, false, false, true
+	case 'b':
+		for i := 0; i < n; i++ {
+			pos = vimWordBackward(lines, pos)
+		}
+		return pos, false, false, true
+	case 'e':
+		for i := 0; i < n; i++ {
+			pos = vimWordEnd(lines, pos)
+		}
+		return pos, false, true, true
+	}
+	return pos, false, false, false
+}
+
+// vimCharAt returns the rune at p, with line ends reported as '\n'
+func vimCharAt(lines [][]rune, p vimPos) rune {
+	line := lines[p.y]
+	if p.x >= len(line) {
+		return '\n'
+	}
+	return line[p.x]
+}
+
+// vimClass classifies a rune as blank (0), word character (1) or punctuation (2)
+func vimClass(r rune) int {
+	switch {
+	case unicode.IsSpace(r):
+		return 0
+	case r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r):
+		return 1
+	default:
+		return 2
+	}
+}
+
+// vimStep moves one position forward (dir > 0) or backward, crossing line ends
+func vimStep(lines [][]rune, p vimPos, dir int) (vimPos, bool) {
+	if dir > 0 {
+		if p.x < len(lines[p.y]) {
+			return vimPos{p.x + 1, p.y}, true
+		}
+		if p.y+1 < len(lines) {
+			return vimPos{0, p.y + 1}, true
+		}
+		return p, false
+	}
+	if p.x > 0 {
+		return vimPos{p.x - 1, p.y}, true
+	}
+	if p.y > 0 {
+		return vimPos{len(lines[p.y-1]), p.y - 1}, true
+	}
+	return p, false
+}
+
+// vimEmptyLine reports whether p is on an empty line (which counts as a word)
+func vimEmptyLine(lines [][]rune, p vimPos) bool {
+	return len(lines[p.y]) == 0
+}
+
+// vimWordForward implements the "w" motion
+func vimWordForward(lines [][]rune, p vimPos) vimPos {
+	q, ok := p, true
+	if cls := vimClass(vimCharAt(lines, q)); cls != 0 {
+		for ok && vimClass(vimCharAt(lines, q)) == cls {
+			q, ok = vimStep(lines, q, 1)
+		}
+	}
+	for ok && vimClass(vimCharAt(lines, q)) == 0 {
+		if q.y != p.y && vimEmptyLine(lines, q) {
+			break
+		}
+		q, ok = vimStep(lines, q, 1)
+	}
+	return q
+}
+
+// vimWordBackward implements the "b" motion
+func vimWordBackward(lines [][]rune, p vimPos) vimPos {
+	q, ok := vimStep(lines, p, -1)
+	for ok && vimClass(vimCharAt(lines, q)) == 0 {
+		if q.y != p.y && vimEmptyLine(lines, q) {
+			return q
+		}
+		q, ok = vimStep(lines, q, -1)
+	}
+	cls := vimClass(vimCharAt(lines, q))
+	for {
+		prev, ok := vimStep(lines, q, -1)
+		if !ok || vimClass(vimCharAt(lines, prev)) != cls {
+			return q
+		}
+		q = prev
+	}
+}
+
+// vimWordEnd implements the "e" motion
+func vimWordEnd(lines [][]rune, p vimPos) vimPos {
+	q, ok := vimStep(lines, p, 1)
+	for ok && vimClass(vimCharAt(lines, q)) == 0 {
+		q, ok = vimStep(lines, q, 1)
+	}
+	cls := vimClass(vimCharAt(lines, q))
+	for {
+		next, ok := vimStep(lines, q, 1)
+		if !ok || vimClass(vimCharAt(lines, next)) != cls {
+			return q
+		}
+		q = next
+	}
+}
+
+// vimFind finds the next (or previous) occurrence of pattern, wrapping around
+func vimFind(lines [][]rune, from vimPos, pattern string, forward bool) (vimPos, bool) {
+	flat := vimFlatten(lines)
+	needle := []rune(pattern)
+	if len(needle) == 0 || len(flat) == 0 {
+		return from, false
+	}
+
+	start := vimOffset(lines, from)
+	for k := 1; k <= len(flat); k++ {
+		idx := start - k
+		if forward {
+			idx = start + k
+		}
+		idx = ((idx % len(flat)) + len(flat)) % len(flat)
+		if vimMatchAt(flat, needle, idx) {
+			return vimPosAt(lines, idx), true
+		}
+	}
+	return from, false
+}
+
+// vimMatchAt reports whether needle occurs in flat at idx
+func vimMatchAt(flat, needle []rune, idx int) bool {
+	if idx+len(needle) > len(flat) {
+		return false
+	}
+	for i, r := range needle {
+		if flat[idx+i] != r {
+			return false
+		}
+	}
+	return true
+}
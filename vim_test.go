@@ -0,0 +1,76 @@
+package main
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/awesome-gocui/gocui"
+)
+
+func TestVimVisualSelection(t *testing.T) {
+	note := "# Note\nfirst line\nsecond line\nthird line\n"
+	app := newTestApp(t, map[string]string{"note.md": note})
+	selectFile(t, app, "note.md")
+	app.vimEnabled = true
+	v, err := app.gui.View(MAIN_VIEW)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := app.enterEditMode(app.gui, v); err != nil {
+		t.Fatal(err)
+	}
+	text := v.BufferLines()
+	keys := func(s string) {
+		for _, ch := range s {
+			app.vimEdit(v, 0, ch, gocui.ModNone)
+		}
+	}
+
+	// v at column 6 of line 1, two characters right, one line down
+	v.SetCursor(6, 1)
+	keys("vllj")
+	want := vimSelection{from: vimPos{6, 1}, to: vimPos{8, 2}, shown: true}
+	if app.vim.painted != want {
+		t.Fatalf("painted %+v, want %+v", app.vim.painted, want)
+	}
+	if !strings.Contains(app.vimStatus(), "2 lines selected") || app.vim.mode.String() != "VISUAL" {
+		t.Errorf("status %q in mode %s", app.vimStatus(), app.vim.mode)
+	}
+	if !reflect.DeepEqual(v.BufferLines(), text) {
+		t.Fatalf("highlighting changed the text: %q", v.BufferLines())
+	}
+
+	// Moving back above the anchor flips the selection; V selects whole lines
+	keys("kk")
+	if sel := app.vim.painted; sel.from != (vimPos{5, 0}) || sel.to != (vimPos{6, 1}) {
+		t.Errorf("selection after kk is %+v", sel)
+	}
+	keys("V")
+	if sel := app.vim.painted; !sel.linewise || !sel.covers(0) || !sel.covers(1) || sel.covers(2) {
+		t.Errorf("line selection is %+v", sel)
+	}
+	if app.vim.mode.String() != "V-LINE" {
+		t.Errorf("mode %s, want V-LINE", app.vim.mode)
+	}
+
+	// Esc drops the selection
+	app.vimEscape(v)
+	if app.vim.painted.shown || app.vimStatus() != "" {
+		t.Errorf("selection still shown after Esc: %+v %q", app.vim.painted, app.vimStatus())
+	}
+	if !reflect.DeepEqual(v.BufferLines(), text) {
+		t.Errorf("text changed: %q", v.BufferLines())
+	}
+
+	// A selection on one line counts characters; y copies it and ends it
+	v.SetCursor(0, 2)
+	keys("vee")
+	if got := app.vimStatus(); !strings.Contains(got, "11 chars selected") {
+		t.Errorf("status %q, want 11 chars", got)
+	}
+	keys("y")
+	if app.vim.painted.shown || app.vim.register != "second line" {
+		t.Errorf("after y: painted %v, register %q", app.vim.painted.shown, app.vim.register)
+	}
+}
@@ -0,0 +1,234 @@
+package main
+
+import (
+	"github.com/awesome-gocui/gocui"
+	"github.com/mattn/go-runewidth"
+)
+
+// =============================================================================
+// WRAPPED LINES
+// =============================================================================
+
+// wrapRows returns the index of the first rune of each visual row of a line
+// wrapped at width columns, the same way gocui wraps view lines
+func wrapRows(line []rune, width int) []int {
+	starts := []int{0}
+	if width <= 0 {
+		return starts
+	}
+
+	used := 0
+	for i, r := range line {
+		w := runewidth.RuneWidth(r)
+		if used+w > width && used > 0 {
+			starts = append(starts, i)
+			used = 0
+		}
+		used += w
+	}
+	return starts
+}
+
+// lineRow returns the wrapped row where line y starts
+func lineRow(lines []string, y, width int) int {
+	row := 0
+	for _, line := range lines[:y] {
+		row += len(wrapRows([]rune(line), width))
+	}
+	return row
+}
+
+// placeCursorLine scrolls a wrapping view so the cursor's line starts screenRow rows from the top
+func placeCursorLine(v *gocui.View, screenRow int) {
+	width, _ := v.Size()
+	lines := v.BufferLines()
+	_, cy := v.Cursor()
+	if cy > len(lines) {
+		cy = len(lines)
+	}
+	origin := lineRow(lines, cy, width) - screenRow
+	if origin < 0 {
+		origin = 0
+	}
+	v.SetOrigin(0, origin)
+}
+
+// runesWidth returns the display width of runes
+func runesWidth(runes []rune) int {
+	width := 0
+	for _, r := range runes {
+		width += runewidth.RuneWidth(r)
+	}
+	return width
+}
+
+// cursorScreenPos returns the cursor position inside the view's visible area
+func cursorScreenPos(v *gocui.View) (int, int) {
+	cx, cy := v.Cursor()
+	ox, oy := v.Origin()
+	if !v.Wrap {
+		return cx - ox, cy - oy
+	}
+
+	width, _ := v.Size()
+	lines := v.BufferLines()
+	row := 0
+	for i := 0; i < cy && i < len(lines); i++ {
+		row += len(wrapRows([]rune(lines[i]), width))
+	}
+
+	var line []rune
+	if cy < len(lines) {
+		line = []rune(lines[cy])
+	}
+	if cx > len(line) {
+		cx = len(line)
+	}
+	starts := wrapRows(line, width)
+	r := len(starts) - 1
+	for r > 0 && starts[r] > cx {
+		r--
+	}
+	col := runesWidth(line[starts[r]:cx])
+	if width > 0 && col >= width {
+		// The cursor sits past a full row, at the start of the next one
+		r++
+		col -= width
+	}
+	return col, row + r - oy
+}
+
+// =============================================================================
+// VISUAL ROW MOVEMENT
+// =============================================================================
+
+// cursorGoal remembers the screen column vertical movement aims for, so moving
+// through a short row doesn't lose the column
+type cursorGoal struct {
+	x, y int // cursor position the goal applies to
+	col  int // screen column within the row
+}
+
+// visualRow returns which wrapped row of a line holds rune index x
+func visualRow(starts []int, x int) int {
+	r := len(starts) - 1
+	for r > 0 && starts[r] > x {
+		r--
+	}
+	return r
+}
+
+// rowBounds returns the rune range [start, end) of wrapped row r of a line
+func rowBounds(line []rune, starts []int, r int) (int, int) {
+	if r+1 < len(starts) {
+		return starts[r], starts[r+1]
+	}
+	return starts[r], len(line)
+}
+
+// columnIndex returns the rune index in [start, end] closest to screen column col
+func columnIndex(line []rune, start, end, col int) int {
+	used := 0
+	for i := start; i < end; i++ {
+		w := runewidth.RuneWidth(line[i])
+		if used+w > col {
+			return i
+		}
+		used += w
+	}
+	return end
+}
+
+// moveCursorVisual moves the edit cursor up (dir < 0) or down (dir > 0) one
+// wrapped row. It reports false when there is no row to move to.
+func (app *App) moveCursorVisual(v *gocui.View, dir int) bool {
+	width, _ := v.Size()
+	lines := v.BufferLines()
+	if len(lines) == 0 {
+		return false
+	}
+	cx, cy := v.Cursor()
+	if cy >= len(lines) {
+		cy = len(lines) - 1
+	}
+	line := []rune(lines[cy])
+	if cx > len(line) {
+		cx = len(line)
+	}
+
+	starts := wrapRows(line, width)
+	r := visualRow(starts, cx)
+	col := runesWidth(line[starts[r]:cx])
+	if app.goal.x == cx && app.goal.y == cy {
+		col = app.goal.col
+	}
+
+	// Find the target row: the neighbour in this line or the edge row of the next line
+	switch {
+	case dir < 0 && r > 0:
+		r--
+	case dir < 0 && cy > 0:
+		cy--
+		line = []rune(lines[cy])
+		starts = wrapRows(line, width)
+		r = len(starts) - 1
+	case dir > 0 && r < len(starts)-1:
+		r++
+	case dir > 0 && cy < len(lines)-1:
+		cy++
+		line = []rune(lines[cy])
+		starts = wrapRows(line, width)
+		r = 0
+	default:
+		return false
+	}
+
+	start, end := rowBounds(line, starts, r)
+	if r+1 < len(starts) && end > start {
+		end-- // The row's end is the first rune of the next row
+	}
+	cx = columnIndex(line, start, end, col)
+	v.SetCursor(cx, cy)
+	app.goal = cursorGoal{x: cx, y: cy, col: col}
+	keepCursorVisible(v)
+	return true
+}
+
+// moveToRowEdge moves the edit cursor to the start or end of its wrapped row
+func (app *App) moveToRowEdge(v *gocui.View, toEnd bool) {
+	width, _ := v.Size()
+	lines := v.BufferLines()
+	cx, cy := v.Cursor()
+	if cy >= len(lines) {
+		return
+	}
+	line := []rune(lines[cy])
+	if cx > len(line) {
+		cx = len(line)
+	}
+
+	starts := wrapRows(line, width)
+	r := visualRow(starts, cx)
+	start, end := rowBounds(line, starts, r)
+	if !toEnd {
+		cx = start
+	} else if r+1 < len(starts) {
+		cx = end - 1 // Stay on this row rather than the start of the next
+	} else {
+		cx = end
+	}
+	v.SetCursor(cx, cy)
+	keepCursorVisible(v)
+}
+
+// keepCursorVisible scrolls a wrapping view the least needed to show the cursor
+func keepCursorVisible(v *gocui.View) {
+	_, height := v.Size()
+	_, row := cursorScreenPos(v)
+	_, oy := v.Origin()
+	if row < 0 {
+		v.SetOrigin(0, oy+row)
+	} else if height > 0 && row >= height {
+		v.SetOrigin(0, oy+row-height+1)
+	}
+}